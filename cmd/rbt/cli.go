@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/avl"
+	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
+	"sqirvy.xyz/go-tree-iterator/codec"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	rbt "sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// tree is an implementation that can be saved to and loaded from a file
+type tree[K constraints.Ordered] interface {
+	rbt.RBT[K, string]
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// create an empty tree of the named implementation
+func newTree[K constraints.Ordered](impl string) (tree[K], error) {
+	switch impl {
+	case "gemini":
+		return gm.NewRBT[K, string](), nil
+	case "copilot":
+		return cp.NewRBT[K, string](), nil
+	case "chatgpt":
+		return ch.NewRBT[K, string](), nil
+	case "avl":
+		return avl.NewRBT[K, string](), nil
+	}
+	return nil, fmt.Errorf("unknown implementation %q, want gemini, copilot, chatgpt or avl", impl)
+}
+
+// options shared by import and export
+type options struct {
+	impl    string
+	keyType string
+	format  string
+	keyCol  int
+	valCol  int
+	header  bool
+	lo, hi  string
+	in, out string
+}
+
+func (o *options) common(fs *flag.FlagSet) {
+	fs.StringVar(&o.keyType, "type", "string", "key type: int, float or string")
+	fs.StringVar(&o.format, "format", "csv", "text format: csv or tsv")
+	fs.StringVar(&o.lo, "lo", "", "smallest key to write, default unbounded")
+	fs.StringVar(&o.hi, "hi", "", "largest key to write, default unbounded")
+}
+
+const usage = `usage:
+  rbt                           run the demo
+  rbt import [flags] [file...]  sort CSV/TSV rows, or load them into a tree file
+  rbt export [flags]            write a tree file as sorted CSV/TSV
+run 'rbt import -h' or 'rbt export -h' for flags`
+
+// run a subcommand, args excludes the program name
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	var o options
+	fs := flag.NewFlagSet("rbt "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	o.common(fs)
+
+	switch args[0] {
+	case "import":
+		fs.StringVar(&o.impl, "impl", "gemini", "tree implementation: gemini, copilot, chatgpt or avl")
+		fs.IntVar(&o.keyCol, "key", 1, "key column, counting from 1")
+		fs.IntVar(&o.valCol, "val", 2, "value column, counting from 1")
+		fs.BoolVar(&o.header, "header", false, "skip the first row of each input")
+		fs.StringVar(&o.out, "o", "", "tree file to write, default write sorted rows to standard output")
+	case "export":
+		fs.StringVar(&o.in, "i", "", "tree file to read (required)")
+		fs.StringVar(&o.out, "o", "", "output file, default standard output")
+	default:
+		return fmt.Errorf("unknown command %q\n%v", args[0], usage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if o.format != "csv" && o.format != "tsv" {
+		return fmt.Errorf("unknown format %q, want csv or tsv", o.format)
+	}
+
+	switch o.keyType {
+	case "int":
+		return dispatch(args[0], &o, fs.Args(), stdin, stdout, func(s string) (int, error) { return strconv.Atoi(s) })
+	case "float":
+		return dispatch(args[0], &o, fs.Args(), stdin, stdout, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+	case "string":
+		return dispatch(args[0], &o, fs.Args(), stdin, stdout, func(s string) (string, error) { return s, nil })
+	}
+	return fmt.Errorf("unknown key type %q, want int, float or string", o.keyType)
+}
+
+func dispatch[K constraints.Ordered](cmd string, o *options, files []string, stdin io.Reader, stdout io.Writer, parse func(string) (K, error)) error {
+	r, err := parseRange(o, parse)
+	if err != nil {
+		return fmt.Errorf("%v: %w", cmd, err)
+	}
+	if cmd == "import" {
+		return importRows(o, r, files, stdin, stdout, parse)
+	}
+	return exportRows(o, r, stdout)
+}
+
+// the keys selected by -lo and -hi, either end may be open
+type keyRange[K constraints.Ordered] struct {
+	lo, hi       K
+	hasLo, hasHi bool
+}
+
+func parseRange[K constraints.Ordered](o *options, parse func(string) (K, error)) (keyRange[K], error) {
+	var r keyRange[K]
+	var err error
+	if o.lo != "" {
+		if r.lo, err = parse(o.lo); err != nil {
+			return r, fmt.Errorf("bad -lo: %w", err)
+		}
+		r.hasLo = true
+	}
+	if o.hi != "" {
+		if r.hi, err = parse(o.hi); err != nil {
+			return r, fmt.Errorf("bad -hi: %w", err)
+		}
+		r.hasHi = true
+	}
+	return r, nil
+}
+
+func (r keyRange[K]) contains(k K) bool {
+	return !(r.hasLo && k < r.lo) && !(r.hasHi && k > r.hi)
+}
+
+// read the rows in range from files, or stdin when there are none, into a tree.
+// write the tree to o.out, or its rows in key order to stdout when there is no o.out
+func importRows[K constraints.Ordered](o *options, r keyRange[K], files []string, stdin io.Reader, stdout io.Writer, parse func(string) (K, error)) error {
+	if o.keyCol < 1 || o.valCol < 1 {
+		return errors.New("import: columns count from 1")
+	}
+	t, err := newTree[K](o.impl)
+	if err != nil {
+		return err
+	}
+
+	load := func(name string, rd io.Reader) error {
+		cr := csv.NewReader(bufio.NewReader(rd))
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		if o.format == "tsv" {
+			cr.Comma = '\t'
+			cr.LazyQuotes = true
+		}
+		for row := 1; ; row++ {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%v: %w", name, err)
+			}
+			if row == 1 && o.header {
+				continue
+			}
+			if len(rec) < max(o.keyCol, o.valCol) {
+				return fmt.Errorf("%v:%v: %v columns, want at least %v", name, row, len(rec), max(o.keyCol, o.valCol))
+			}
+			k, err := parse(strings.TrimSpace(rec[o.keyCol-1]))
+			if err != nil {
+				return fmt.Errorf("%v:%v: bad key: %w", name, row, err)
+			}
+			if r.contains(k) {
+				t.Put(k, rec[o.valCol-1])
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		if err := load("stdin", stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = load(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.out == "" {
+		return writeRows(stdout, o, r, t.Iterator())
+	}
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.out, data, 0o644)
+}
+
+// read the tree file o.in and write its rows in range in key order. the file
+// holds the sorted pairs whatever implementation wrote it, so no tree is built
+func exportRows[K constraints.Ordered](o *options, r keyRange[K], stdout io.Writer) error {
+	if o.in == "" {
+		return errors.New("export: -i is required")
+	}
+	data, err := os.ReadFile(o.in)
+	if err != nil {
+		return err
+	}
+	pairs, err := codec.Unmarshal(data, codec.Default[K](), codec.Default[string]())
+	if err != nil {
+		return fmt.Errorf("%v: %w", o.in, err)
+	}
+
+	if o.out == "" {
+		return writeRows(stdout, o, r, slices.Values(pairs))
+	}
+	f, err := os.Create(o.out)
+	if err != nil {
+		return err
+	}
+	err = writeRows(f, o, r, slices.Values(pairs))
+	// a failed close can mean the rows never reached the disk
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// write the rows of it, in ascending key order, that fall in r
+func writeRows[K constraints.Ordered](w io.Writer, o *options, r keyRange[K], it func(func(rbt.KeyValuePair[K, string]) bool)) error {
+	cw := csv.NewWriter(w)
+	if o.format == "tsv" {
+		cw.Comma = '\t'
+	}
+	for p := range it {
+		if r.hasLo && p.Key < r.lo {
+			continue
+		}
+		if r.hasHi && p.Key > r.hi {
+			break
+		}
+		if err := cw.Write([]string{fmt.Sprint(p.Key), p.Val}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportExport(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.csv")
+	os.WriteFile(in, []byte("name,id,score\nbob,10,1\nann,2,\"x, y\"\ncat,33,3\ndan,2,4\n"), 0o644)
+	file := filepath.Join(dir, "tree")
+
+	for _, impl := range []string{"gemini", "copilot", "chatgpt", "avl"} {
+		err := run([]string{"import", "-impl", impl, "-type", "int", "-header", "-key", "2", "-val", "3", "-o", file, in}, nil, io.Discard, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := run([]string{"export", "-type", "int", "-i", file}, nil, &out, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		// keys sort numerically and the last row for a key wins
+		if want := "2,4\n10,1\n33,3\n"; out.String() != want {
+			t.Errorf("%v: export = %q; want %q", impl, out.String(), want)
+		}
+
+		out.Reset()
+		if err := run([]string{"export", "-type", "int", "-lo", "3", "-hi", "33", "-format", "tsv", "-i", file}, nil, &out, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if want := "10\t1\n33\t3\n"; out.String() != want {
+			t.Errorf("%v: export range = %q; want %q", impl, out.String(), want)
+		}
+	}
+}
+
+func TestImportStdin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tree")
+	stdin := strings.NewReader("2.5\tb\n-1\ta\n10\tc\n")
+	if err := run([]string{"import", "-type", "float", "-format", "tsv", "-o", file}, stdin, io.Discard, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run([]string{"export", "-type", "float", "-hi", "3", "-i", file}, nil, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if want := "-1,a\n2.5,b\n"; out.String() != want {
+		t.Errorf("export = %q; want %q", out.String(), want)
+	}
+
+	csvFile := filepath.Join(t.TempDir(), "out.csv")
+	if err := run([]string{"export", "-type", "float", "-lo", "0", "-i", file, "-o", csvFile}, nil, io.Discard, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(csvFile); string(data) != "2.5,b\n10,c\n" {
+		t.Errorf("export -o wrote %q", data)
+	}
+
+	// the key type is recorded in the file
+	if err := run([]string{"export", "-type", "string", "-i", file}, nil, io.Discard, io.Discard); err == nil {
+		t.Errorf("export with the wrong key type: err = nil")
+	}
+}
+
+// without -o import writes the sorted rows instead of a tree file
+func TestImportSort(t *testing.T) {
+	for _, impl := range []string{"gemini", "copilot", "chatgpt", "avl"} {
+		stdin := strings.NewReader("pear,3\napple,1\nfig,2\nkiwi,4\napple,5\n")
+		var out bytes.Buffer
+		if err := run([]string{"import", "-impl", impl, "-lo", "b", "-hi", "l"}, stdin, &out, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if want := "fig,2\nkiwi,4\n"; out.String() != want {
+			t.Errorf("%v: import = %q; want %q", impl, out.String(), want)
+		}
+	}
+	var out bytes.Buffer
+	if err := run([]string{"import", "-type", "int", "-format", "tsv"}, strings.NewReader("10\tb\n-1\ta\n"), &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if want := "-1\ta\n10\tb\n"; out.String() != want {
+		t.Errorf("import = %q; want %q", out.String(), want)
+	}
+}
+
+func TestBadArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tree")
+	for _, args := range [][]string{
+		{},
+		{"frob"},
+		{"import", "-o", file, "-impl", "splay"},
+		{"import", "-o", file, "-type", "bool"},
+		{"import", "-o", file, "-format", "json"},
+		{"import", "-type", "int", "-lo", "x"},
+		{"export"},
+		{"export", "-impl", "avl", "-i", file},
+	} {
+		if err := run(args, strings.NewReader(""), io.Discard, io.Discard); err == nil {
+			t.Errorf("run(%q) err = nil", args)
+		}
+	}
+	if err := run([]string{"import", "-type", "int", "-o", file}, strings.NewReader("x,1\n"), io.Discard, io.Discard); err == nil || !strings.Contains(err.Error(), "stdin:1") {
+		t.Errorf("bad key err = %v; want it to name the row", err)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"os"
 
+	"sqirvy.xyz/go-tree-iterator/avl"
 	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
 	cp "sqirvy.xyz/go-tree-iterator/copilot"
 	gm "sqirvy.xyz/go-tree-iterator/gemini"
@@ -44,7 +46,15 @@ func runRbt(s string, t rbt.RBT[int, string]) {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	runRbt("=== Copilot ===", cp.NewRBT[int, string]())
 	runRbt("=== Gemini ===", gm.NewRBT[int, string]())
 	runRbt("=== ChatGpt ===", ch.NewRBT[int, string]())
+	runRbt("=== Avl ===", avl.NewRBT[int, string]())
 }
@@ -0,0 +1,600 @@
+package arena
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// the gemini left leaning red-black tree with its nodes in one slice. a
+// node links to its children by their int32 index in the slice instead of
+// by pointer, and its color shares a word with its subtree size. with keys
+// and values that hold no pointers, like ints, the whole tree is a single
+// pointer-free allocation the garbage collector never has to scan, where
+// the pointer version is one object per key for it to trace.
+// slot 0 stands for nil, and deleted slots go on a free list threaded
+// through their left links, to be reused before the slice grows.
+
+// the color bit of node.sc, the rest is the size of the subtree
+const red = 1 << 31
+
+type node[K constraints.Ordered, V any] struct {
+	key         K
+	val         V
+	left, right int32
+	sc          uint32 // size and color
+}
+
+type ArenaRBT[K constraints.Ordered, V any] struct {
+	codec.Codecs[K, V] // key and value codecs for MarshalBinary
+
+	nodes     []node[K, V]
+	root      int32
+	free      int32 // first slot of the free list, 0 when it is empty
+	onChange  func(rbt.Event[K, V])
+	rotations int
+}
+
+func NewRBT[K constraints.Ordered, V any]() *ArenaRBT[K, V] {
+	return &ArenaRBT[K, V]{}
+}
+
+// create a tree with room for n keys before the arena has to grow
+func NewRBTWithCapacity[K constraints.Ordered, V any](n int) *ArenaRBT[K, V] {
+	return &ArenaRBT[K, V]{nodes: make([]node[K, V], 1, n+1)}
+}
+
+// get a slot for a new red node, from the free list if it has one
+func (t *ArenaRBT[K, V]) alloc(key K, val V) int32 {
+	if t.free != 0 {
+		x := t.free
+		t.free = t.nodes[x].left
+		t.nodes[x] = node[K, V]{key: key, val: val, sc: red | 1}
+		return x
+	}
+	if len(t.nodes) == 0 {
+		t.nodes = append(t.nodes, node[K, V]{})
+	}
+	if len(t.nodes) > 1<<31-1 {
+		panic("arena: more than 2^31-1 nodes")
+	}
+	t.nodes = append(t.nodes, node[K, V]{key: key, val: val, sc: red | 1})
+	return int32(len(t.nodes) - 1)
+}
+
+// put slot x on the free list, clearing it so it holds on to nothing
+func (t *ArenaRBT[K, V]) release(x int32) {
+	t.nodes[x] = node[K, V]{left: t.free}
+	t.free = x
+}
+
+func (t *ArenaRBT[K, V]) IsEmpty() bool {
+	return t.root == 0
+}
+
+func (t *ArenaRBT[K, V]) Size() int {
+	return t.size(t.root)
+}
+
+// the number of slots in the arena, in use or free
+func (t *ArenaRBT[K, V]) Slots() int {
+	return max(len(t.nodes)-1, 0)
+}
+
+func (t *ArenaRBT[K, V]) size(x int32) int {
+	if x == 0 {
+		return 0
+	}
+	return int(t.nodes[x].sc &^ red)
+}
+
+func (t *ArenaRBT[K, V]) setSize(x int32, n int) {
+	t.nodes[x].sc = t.nodes[x].sc&red | uint32(n)
+}
+
+func (t *ArenaRBT[K, V]) isRed(x int32) bool {
+	return x != 0 && t.nodes[x].sc&red != 0
+}
+
+// IsRed matches gemini's, with the node given by its slot. slot 0 is nil
+// and black
+func (t *ArenaRBT[K, V]) IsRed(x int32) bool {
+	return t.isRed(x)
+}
+
+func (t *ArenaRBT[K, V]) setRed(x int32, r bool) {
+	if r {
+		t.nodes[x].sc |= red
+	} else {
+		t.nodes[x].sc &^= red
+	}
+}
+
+func (t *ArenaRBT[K, V]) Get(key K) (V, bool) {
+	x := t.get(t.root, key)
+	if x == 0 {
+		var zero V
+		return zero, false
+	}
+	return t.nodes[x].val, true
+}
+
+func (t *ArenaRBT[K, V]) get(x int32, key K) int32 {
+	for x != 0 {
+		n := &t.nodes[x]
+		cmp := compare(key, n.key)
+		if cmp < 0 {
+			x = n.left
+		} else if cmp > 0 {
+			x = n.right
+		} else {
+			return x
+		}
+	}
+	return 0
+}
+
+func (t *ArenaRBT[K, V]) Put(key K, val V) {
+	ev := rbt.Event[K, V]{Kind: rbt.EventPut, Key: key, New: val}
+	if t.onChange != nil {
+		if x := t.get(t.root, key); x != 0 {
+			ev.Kind = rbt.EventUpdate
+			ev.Old = t.nodes[x].val
+		}
+	}
+	t.root = t.put(t.root, key, val)
+	t.setRed(t.root, false)
+	t.notify(ev)
+}
+
+// put may grow the arena, so it only takes the address of a node after the
+// recursive call returns
+func (t *ArenaRBT[K, V]) put(h int32, key K, val V) int32 {
+	if h == 0 {
+		return t.alloc(key, val)
+	}
+	cmp := compare(key, t.nodes[h].key)
+	if cmp < 0 {
+		l := t.put(t.nodes[h].left, key, val)
+		t.nodes[h].left = l
+	} else if cmp > 0 {
+		r := t.put(t.nodes[h].right, key, val)
+		t.nodes[h].right = r
+	} else {
+		t.nodes[h].val = val
+		return h
+	}
+	if !t.isRed(t.nodes[h].left) && t.isRed(t.nodes[h].right) {
+		h = t.rotateLeft(h)
+	}
+	if t.isRed(t.nodes[h].left) && t.isRed(t.nodes[t.nodes[h].left].left) {
+		h = t.rotateRight(h)
+	}
+	if t.isRed(t.nodes[h].left) && t.isRed(t.nodes[h].right) {
+		t.flipColors(h)
+	}
+	t.setSize(h, 1+t.size(t.nodes[h].left)+t.size(t.nodes[h].right))
+	return h
+}
+
+func (t *ArenaRBT[K, V]) Min() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.nodes[t.min(t.root)].key, true
+}
+
+func (t *ArenaRBT[K, V]) min(x int32) int32 {
+	for t.nodes[x].left != 0 {
+		x = t.nodes[x].left
+	}
+	return x
+}
+
+func (t *ArenaRBT[K, V]) Max() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.nodes[t.max(t.root)].key, true
+}
+
+func (t *ArenaRBT[K, V]) max(x int32) int32 {
+	for t.nodes[x].right != 0 {
+		x = t.nodes[x].right
+	}
+	return x
+}
+
+func (t *ArenaRBT[K, V]) Floor(key K) (K, bool) {
+	x := t.floor(t.root, key)
+	if x == 0 {
+		var zero K
+		return zero, false
+	}
+	return t.nodes[x].key, true
+}
+
+// the node with the largest key <= key, 0 if there is none
+func (t *ArenaRBT[K, V]) floor(x int32, key K) int32 {
+	var best int32
+	for x != 0 {
+		cmp := compare(key, t.nodes[x].key)
+		if cmp < 0 {
+			x = t.nodes[x].left
+		} else if cmp == 0 {
+			return x
+		} else {
+			best = x
+			x = t.nodes[x].right
+		}
+	}
+	return best
+}
+
+func (t *ArenaRBT[K, V]) Ceiling(key K) (K, bool) {
+	x := t.ceiling(t.root, key)
+	if x == 0 {
+		var zero K
+		return zero, false
+	}
+	return t.nodes[x].key, true
+}
+
+// the node with the smallest key >= key, 0 if there is none
+func (t *ArenaRBT[K, V]) ceiling(x int32, key K) int32 {
+	var best int32
+	for x != 0 {
+		cmp := compare(key, t.nodes[x].key)
+		if cmp > 0 {
+			x = t.nodes[x].right
+		} else if cmp == 0 {
+			return x
+		} else {
+			best = x
+			x = t.nodes[x].left
+		}
+	}
+	return best
+}
+
+func (t *ArenaRBT[K, V]) Select(k int) (K, bool) {
+	x := t.root
+	for x != 0 {
+		l := t.size(t.nodes[x].left)
+		if l > k {
+			x = t.nodes[x].left
+		} else if l < k {
+			k -= l + 1
+			x = t.nodes[x].right
+		} else {
+			return t.nodes[x].key, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+func (t *ArenaRBT[K, V]) Rank(key K) int {
+	r := 0
+	for x := t.root; x != 0; {
+		cmp := compare(key, t.nodes[x].key)
+		if cmp < 0 {
+			x = t.nodes[x].left
+		} else if cmp > 0 {
+			r += 1 + t.size(t.nodes[x].left)
+			x = t.nodes[x].right
+		} else {
+			return r + t.size(t.nodes[x].left)
+		}
+	}
+	return r
+}
+
+func (t *ArenaRBT[K, V]) DeleteMin() {
+	if t.IsEmpty() {
+		return
+	}
+	x := t.min(t.root)
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: t.nodes[x].key, Old: t.nodes[x].val}
+	if !t.isRed(t.nodes[t.root].left) && !t.isRed(t.nodes[t.root].right) {
+		t.setRed(t.root, true)
+	}
+	t.root = t.deleteMin(t.root)
+	if !t.IsEmpty() {
+		t.setRed(t.root, false)
+	}
+	t.notify(ev)
+}
+
+func (t *ArenaRBT[K, V]) deleteMin(h int32) int32 {
+	if t.nodes[h].left == 0 {
+		r := t.nodes[h].right
+		t.release(h)
+		return r
+	}
+	if !t.isRed(t.nodes[h].left) && !t.isRed(t.nodes[t.nodes[h].left].left) {
+		h = t.moveRedLeft(h)
+	}
+	t.nodes[h].left = t.deleteMin(t.nodes[h].left)
+	return t.balance(h)
+}
+
+func (t *ArenaRBT[K, V]) DeleteMax() {
+	if t.IsEmpty() {
+		return
+	}
+	x := t.max(t.root)
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: t.nodes[x].key, Old: t.nodes[x].val}
+	if !t.isRed(t.nodes[t.root].left) && !t.isRed(t.nodes[t.root].right) {
+		t.setRed(t.root, true)
+	}
+	t.root = t.deleteMax(t.root)
+	if !t.IsEmpty() {
+		t.setRed(t.root, false)
+	}
+	t.notify(ev)
+}
+
+func (t *ArenaRBT[K, V]) deleteMax(h int32) int32 {
+	if t.isRed(t.nodes[h].left) {
+		h = t.rotateRight(h)
+	}
+	if t.nodes[h].right == 0 {
+		l := t.nodes[h].left
+		t.release(h)
+		return l
+	}
+	if !t.isRed(t.nodes[h].right) && !t.isRed(t.nodes[t.nodes[h].right].left) {
+		h = t.moveRedRight(h)
+	}
+	t.nodes[h].right = t.deleteMax(t.nodes[h].right)
+	return t.balance(h)
+}
+
+func (t *ArenaRBT[K, V]) Delete(key K) {
+	x := t.get(t.root, key)
+	if x == 0 {
+		return
+	}
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: key, Old: t.nodes[x].val}
+	if !t.isRed(t.nodes[t.root].left) && !t.isRed(t.nodes[t.root].right) {
+		t.setRed(t.root, true)
+	}
+	t.root = t.delete(t.root, key)
+	if !t.IsEmpty() {
+		t.setRed(t.root, false)
+	}
+	t.notify(ev)
+}
+
+// OnChange registers fn to be called after every Put, Delete, DeleteMin and
+// DeleteMax that changes the tree. nil removes the callback
+func (t *ArenaRBT[K, V]) OnChange(fn func(rbt.Event[K, V])) {
+	t.onChange = fn
+}
+
+func (t *ArenaRBT[K, V]) notify(ev rbt.Event[K, V]) {
+	if t.onChange != nil {
+		t.onChange(ev)
+	}
+}
+
+func (t *ArenaRBT[K, V]) delete(h int32, key K) int32 {
+	if compare(key, t.nodes[h].key) < 0 {
+		if !t.isRed(t.nodes[h].left) && !t.isRed(t.nodes[t.nodes[h].left].left) {
+			h = t.moveRedLeft(h)
+		}
+		t.nodes[h].left = t.delete(t.nodes[h].left, key)
+	} else {
+		if t.isRed(t.nodes[h].left) {
+			h = t.rotateRight(h)
+		}
+		if compare(key, t.nodes[h].key) == 0 && t.nodes[h].right == 0 {
+			t.release(h)
+			return 0
+		}
+		if !t.isRed(t.nodes[h].right) && !t.isRed(t.nodes[t.nodes[h].right].left) {
+			h = t.moveRedRight(h)
+		}
+		if compare(key, t.nodes[h].key) == 0 {
+			x := t.min(t.nodes[h].right)
+			t.nodes[h].key = t.nodes[x].key
+			t.nodes[h].val = t.nodes[x].val
+			t.nodes[h].right = t.deleteMin(t.nodes[h].right)
+		} else {
+			t.nodes[h].right = t.delete(t.nodes[h].right, key)
+		}
+	}
+	return t.balance(h)
+}
+
+func (t *ArenaRBT[K, V]) Contains(key K) bool {
+	return t.get(t.root, key) != 0
+}
+
+func (t *ArenaRBT[K, V]) Keys() []K {
+	queue := make([]K, 0, t.Size())
+	for r := range t.Iterator() {
+		queue = append(queue, r.Key)
+	}
+	return queue
+}
+
+func (t *ArenaRBT[K, V]) KeysInOrder(lo K, hi K) []K {
+	queue := make([]K, 0)
+	for r := range t.Range(lo, hi) {
+		queue = append(queue, r.Key)
+	}
+	return queue
+}
+
+// the number of keys in [lo..hi], from two ranks
+func (t *ArenaRBT[K, V]) SizeInOrder(lo K, hi K) int {
+	if lo > hi {
+		return 0
+	}
+	n := t.Rank(hi) - t.Rank(lo)
+	if t.Contains(hi) {
+		n++
+	}
+	return n
+}
+
+// the number of rotations done since the tree was created
+func (t *ArenaRBT[K, V]) Rotations() int {
+	return t.rotations
+}
+
+func (t *ArenaRBT[K, V]) Height() int {
+	return t.height(t.root)
+}
+
+func (t *ArenaRBT[K, V]) height(x int32) int {
+	if x == 0 {
+		return -1
+	}
+	return 1 + max(t.height(t.nodes[x].left), t.height(t.nodes[x].right))
+}
+
+func (t *ArenaRBT[K, V]) rotateLeft(h int32) int32 {
+	t.rotations++
+	x := t.nodes[h].right
+	t.nodes[h].right = t.nodes[x].left
+	t.nodes[x].left = h
+	t.setRed(x, t.isRed(h))
+	t.setRed(h, true)
+	t.setSize(x, t.size(h))
+	t.setSize(h, 1+t.size(t.nodes[h].left)+t.size(t.nodes[h].right))
+	return x
+}
+
+func (t *ArenaRBT[K, V]) rotateRight(h int32) int32 {
+	t.rotations++
+	x := t.nodes[h].left
+	t.nodes[h].left = t.nodes[x].right
+	t.nodes[x].right = h
+	t.setRed(x, t.isRed(h))
+	t.setRed(h, true)
+	t.setSize(x, t.size(h))
+	t.setSize(h, 1+t.size(t.nodes[h].left)+t.size(t.nodes[h].right))
+	return x
+}
+
+func (t *ArenaRBT[K, V]) flipColors(h int32) {
+	t.nodes[h].sc ^= red
+	t.nodes[t.nodes[h].left].sc ^= red
+	t.nodes[t.nodes[h].right].sc ^= red
+}
+
+func (t *ArenaRBT[K, V]) moveRedLeft(h int32) int32 {
+	t.flipColors(h)
+	if t.isRed(t.nodes[t.nodes[h].right].left) {
+		t.nodes[h].right = t.rotateRight(t.nodes[h].right)
+		h = t.rotateLeft(h)
+		t.flipColors(h)
+	}
+	return h
+}
+
+func (t *ArenaRBT[K, V]) moveRedRight(h int32) int32 {
+	t.flipColors(h)
+	if t.isRed(t.nodes[t.nodes[h].left].left) {
+		h = t.rotateRight(h)
+		t.flipColors(h)
+	}
+	return h
+}
+
+func (t *ArenaRBT[K, V]) balance(h int32) int32 {
+	if t.isRed(t.nodes[h].right) && !t.isRed(t.nodes[h].left) {
+		h = t.rotateLeft(h)
+	}
+	if t.isRed(t.nodes[h].left) && t.isRed(t.nodes[t.nodes[h].left].left) {
+		h = t.rotateRight(h)
+	}
+	if t.isRed(t.nodes[h].left) && t.isRed(t.nodes[h].right) {
+		t.flipColors(h)
+	}
+	t.setSize(h, 1+t.size(t.nodes[h].left)+t.size(t.nodes[h].right))
+	return h
+}
+
+func compare[K constraints.Ordered](v1 K, v2 K) int {
+	if v1 < v2 {
+		return -1
+	} else if v1 > v2 {
+		return 1
+	} else {
+		return 0
+	}
+}
+
+func (t *ArenaRBT[K, V]) Print() {
+	t.print(t.root, 0)
+}
+
+func (t *ArenaRBT[K, V]) print(x int32, depth int) {
+	if x == 0 {
+		return
+	}
+	n := t.nodes[x]
+	t.print(n.right, depth+1)
+	for i := 0; i < depth; i++ {
+		fmt.Print("  ")
+	}
+	if t.isRed(x) {
+		fmt.Printf("R: %v %v\n", n.key, n.val)
+	} else {
+		fmt.Printf("B: %v %v\n", n.key, n.val)
+	}
+	t.print(n.left, depth+1)
+}
+
+func (t *ArenaRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, t.Size())
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (t *ArenaRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(int32) bool
+		inorder = func(x int32) bool {
+			if x == 0 {
+				return true
+			}
+			n := &t.nodes[x]
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) &&
+				inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (t *ArenaRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(int32) bool
+		inorder = func(x int32) bool {
+			if x == 0 {
+				return true
+			}
+			n := &t.nodes[x]
+			if lo < n.key && !inorder(n.left) {
+				return false
+			}
+			if lo <= n.key && n.key <= hi && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
+				return false
+			}
+			return hi <= n.key || inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
@@ -0,0 +1,695 @@
+package arena
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// test that Nearest yields keys in distance order with ties going to the smaller key
+func TestNearest(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for _, k := range []int{1, 4, 6, 10, 14, 20} {
+		rbt.Put(k, strconv.Itoa(k))
+	}
+
+	want := []int{10, 6, 14, 4, 1}
+	got := make([]int, 0)
+	for r := range Nearest(rbt, 10, 5) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(10, 5) = %v; want %v", got, want)
+	}
+
+	// probe between keys, 4 and 6 are both 1 away from 5
+	want = []int{4, 6, 1}
+	got = got[:0]
+	for r := range Nearest(rbt, 5, 3) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(5, 3) = %v; want %v", got, want)
+	}
+
+	// k larger than the tree yields everything
+	got = got[:0]
+	for r := range Nearest(rbt, 100, 10) {
+		got = append(got, r.Key)
+	}
+	want = []int{20, 14, 10, 6, 4, 1}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(100, 10) = %v; want %v", got, want)
+	}
+}
+
+// test WithinDistance with float keys
+func TestWithinDistance(t *testing.T) {
+	rbt := NewRBT[float64, string]()
+	for _, k := range []float64{0.5, 1.0, 1.25, 2.0, 3.5} {
+		rbt.Put(k, strconv.FormatFloat(k, 'f', -1, 64))
+	}
+
+	want := []float64{1.25, 1.0, 2.0}
+	got := make([]float64, 0)
+	for r := range WithinDistance(rbt, 1.5, 0.5) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(1.5, 0.5) = %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	for r := range WithinDistance(rbt, 10, 1) {
+		got = append(got, r.Key)
+	}
+	if len(got) != 0 {
+		t.Errorf("WithinDistance(10, 1) = %v; want []", got)
+	}
+}
+
+// test keys at the ends of int64, which are further apart than an int64 holds
+func TestNearestLimits(t *testing.T) {
+	rbt := NewRBT[int64, string]()
+	for _, k := range []int64{math.MinInt64, -1, 0, math.MaxInt64} {
+		rbt.Put(k, strconv.FormatInt(k, 10))
+	}
+
+	want := []int64{math.MaxInt64, 0, -1, math.MinInt64}
+	got := make([]int64, 0)
+	for r := range Nearest(rbt, math.MaxInt64, 4) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(MaxInt64, 4) = %v; want %v", got, want)
+	}
+
+	// -1 is 2^63 away, further than any int64 distance
+	want = want[:2]
+	got = got[:0]
+	for r := range WithinDistance(rbt, math.MaxInt64, math.MaxInt64) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(MaxInt64, MaxInt64) = %v; want %v", got, want)
+	}
+}
+
+// test PrefixScan and LongestPrefixOf on path-like keys
+func TestPrefix(t *testing.T) {
+	rbt := NewRBT[string, int]()
+	paths := []string{"/", "/usr", "/usr/bin", "/usr/bin/go", "/usr/lib", "/usrx", "/var", "/var/log"}
+	for i, p := range paths {
+		rbt.Put(p, i)
+	}
+
+	want := []string{"/usr/bin", "/usr/bin/go", "/usr/lib"}
+	got := make([]string, 0)
+	for r := range PrefixScan(rbt, "/usr/") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("PrefixScan(/usr/) = %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	for r := range PrefixScan(rbt, "") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, paths) {
+		t.Errorf("PrefixScan() = %v; want %v", got, paths)
+	}
+
+	tests := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"/usr/bin/gofmt", "/usr/bin/go", true},
+		{"/usr/bin", "/usr/bin", true},
+		{"/usr/local/bin", "/usr", true},
+		{"/usrx/y", "/usrx", true},
+		{"/tmp", "/", true},
+		{"tmp", "", false},
+	}
+	for _, tc := range tests {
+		k, ok := LongestPrefixOf(rbt, tc.s)
+		if k != tc.want || ok != tc.ok {
+			t.Errorf("LongestPrefixOf(%v) = %v, %v; want %v, %v", tc.s, k, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// test Delete, DeleteMin and DeleteMax against a map with random keys
+func TestDeleteRandom(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	m := make(map[int]string)
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(500)
+		switch rand.Intn(4) {
+		case 0, 1:
+			rbt.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			rbt.Delete(k)
+			delete(m, k)
+		case 3:
+			if lo, ok := rbt.Min(); ok {
+				delete(m, lo)
+			}
+			rbt.DeleteMin()
+		}
+		if rbt.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", rbt.Size(), len(m))
+		}
+	}
+
+	for k, v := range m {
+		if x, ok := rbt.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+
+	// a balanced tree of n nodes is never more than 2 lg n high
+	if h := rbt.Height(); len(m) > 0 && h > 2*bits.Len(uint(len(m))) {
+		t.Errorf("Height() = %v for %v keys", h, len(m))
+	}
+}
+
+// test that breaking out of the Iterator stops the traversal
+func TestIteratorBreak(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for i := 0; i < 100; i++ {
+		rbt.Put(i, strconv.Itoa(i))
+	}
+
+	n := 0
+	for r := range rbt.Iterator() {
+		if r.Key == 10 {
+			break
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Iterator() visited %v keys before break; want 10", n)
+	}
+}
+
+// test that ParallelForEach visits every entry exactly once
+func TestParallelForEach(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	for _, k := range rand.Perm(10000) {
+		rbt.Put(k, k)
+	}
+
+	for _, workers := range []int{0, 1, 3, 8, 20000} {
+		var mu sync.Mutex
+		seen := make(map[int]int)
+		rbt.ParallelForEach(workers, func(k int, v int) {
+			mu.Lock()
+			seen[k]++
+			mu.Unlock()
+		})
+		if len(seen) != 10000 {
+			t.Errorf("ParallelForEach(%v) visited %v keys; want 10000", workers, len(seen))
+		}
+		for k, n := range seen {
+			if n != 1 {
+				t.Errorf("ParallelForEach(%v) visited %v %v times", workers, k, n)
+			}
+		}
+	}
+}
+
+// test that ParallelReduce combines partial results in key order
+func TestParallelReduce(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	want := make([]int, 0)
+	for _, k := range rand.Perm(5000) {
+		rbt.Put(k, k*2)
+		want = append(want, k)
+	}
+	slices.Sort(want)
+
+	for _, workers := range []int{1, 4, 7} {
+		got := ParallelReduce(rbt, workers, func() []int { return nil },
+			func(acc []int, k int, v int) []int { return append(acc, k) },
+			func(a, b []int) []int { return append(a, b...) })
+		if !slices.Equal(got, want) {
+			t.Errorf("ParallelReduce(%v) keys not in order", workers)
+		}
+
+		sum := ParallelReduce(rbt, workers, func() int { return 0 },
+			func(acc int, k int, v int) int { return acc + v },
+			func(a, b int) int { return a + b })
+		if sum != 4999*5000 {
+			t.Errorf("ParallelReduce(%v) sum = %v; want %v", workers, sum, 4999*5000)
+		}
+
+		// every worker fills its own map
+		counts := ParallelReduce(rbt, workers, func() map[int]int { return make(map[int]int) },
+			func(acc map[int]int, k int, v int) map[int]int { acc[k%10]++; return acc },
+			func(a, b map[int]int) map[int]int {
+				for k, n := range b {
+					a[k] += n
+				}
+				return a
+			})
+		if len(counts) != 10 || counts[3] != 500 {
+			t.Errorf("ParallelReduce(%v) counts = %v; want 500 of each digit", workers, counts)
+		}
+	}
+
+	empty := NewRBT[int, int]()
+	if sum := ParallelReduce(empty, 4, func() int { return 10 }, func(acc int, k int, v int) int { return acc + v }, func(a, b int) int { return a + b }); sum != 10 {
+		t.Errorf("ParallelReduce on empty tree = %v; want 10", sum)
+	}
+}
+
+// test that ParallelMap returns the results in key order
+func TestParallelMap(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	for _, k := range rand.Perm(5000) {
+		rbt.Put(k, k*2)
+	}
+	for _, workers := range []int{0, 1, 4, 7} {
+		got := ParallelMap(rbt, workers, func(k int, v int) int { return k + v })
+		if len(got) != 5000 {
+			t.Fatalf("ParallelMap(%v) has %v results; want 5000", workers, len(got))
+		}
+		for i, r := range got {
+			if r != 3*i {
+				t.Fatalf("ParallelMap(%v)[%v] = %v; want %v", workers, i, r, 3*i)
+			}
+		}
+	}
+	if got := ParallelMap(NewRBT[int, int](), 4, func(k int, v int) int { return k }); len(got) != 0 {
+		t.Errorf("ParallelMap on empty tree = %v; want []", got)
+	}
+}
+
+// check the left leaning red-black invariants and subtree sizes, return the black height
+func checkRbt[K constraints.Ordered, V any](t *testing.T, a *ArenaRBT[K, V], x int32) int {
+	if x == 0 {
+		return 0
+	}
+	n := &a.nodes[x]
+	if a.isRed(n.right) {
+		t.Fatalf("right leaning red link at %v", n.key)
+	}
+	if a.isRed(x) && a.isRed(n.left) {
+		t.Fatalf("two red links in a row at %v", n.key)
+	}
+	if size := 1 + a.size(n.left) + a.size(n.right); a.size(x) != size {
+		t.Fatalf("size = %v at %v; want %v", a.size(x), n.key, size)
+	}
+	lh, rh := checkRbt(t, a, n.left), checkRbt(t, a, n.right)
+	if lh != rh {
+		t.Fatalf("black heights %v and %v differ at %v", lh, rh, n.key)
+	}
+	if !a.isRed(x) {
+		lh++
+	}
+	return lh
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		src := NewRBT[int, string]()
+		for _, k := range rand.Perm(n) {
+			src.Put(k, strconv.Itoa(k))
+		}
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := NewRBT[int, string]()
+		dst.Put(-1, "replaced")
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if dst.isRed(dst.root) {
+			t.Fatalf("n = %v: red root", n)
+		}
+		checkRbt(t, dst, dst.root)
+		if !slices.Equal(dst.GetAll(), src.GetAll()) {
+			t.Fatalf("n = %v: GetAll() = %v; want %v", n, dst.GetAll(), src.GetAll())
+		}
+
+		// the rebuilt tree must stay valid under further updates
+		dst.Put(n, "new")
+		dst.DeleteMin()
+		checkRbt(t, dst, dst.root)
+	}
+}
+
+func TestMarshalGob(t *testing.T) {
+	type point struct{ X, Y float64 }
+	src := NewRBT[string, point]()
+	for i := 0; i < 100; i++ {
+		src.Put(strconv.Itoa(i), point{float64(i), -float64(i)})
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewRBT[string, point]()
+	if err := gob.NewDecoder(&buf).Decode(dst); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("GetAll() = %v; want %v", dst.GetAll(), src.GetAll())
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	src := NewRBT[int, string]()
+	for i := 0; i < 10; i++ {
+		src.Put(i, strconv.Itoa(i))
+	}
+	data, _ := src.MarshalBinary()
+
+	corrupt := slices.Clone(data)
+	corrupt[len(corrupt)/2] ^= 0xff
+	if err := NewRBT[int, string]().UnmarshalBinary(corrupt); !errors.Is(err, codec.ErrChecksum) {
+		t.Errorf("corrupt stream: err = %v; want %v", err, codec.ErrChecksum)
+	}
+	if err := NewRBT[int, string]().UnmarshalBinary(data[:3]); !errors.Is(err, codec.ErrFormat) {
+		t.Errorf("short stream: err = %v; want %v", err, codec.ErrFormat)
+	}
+	if err := NewRBT[int, int]().UnmarshalBinary(data); err == nil {
+		t.Errorf("mismatched value codec: err = nil")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	words := NewRBT[string, int]()
+	for i, w := range []string{"pear", "apple", "fig"} {
+		words.Put(w, i)
+	}
+	data, err := json.Marshal(words)
+	if err != nil || string(data) != `{"apple":1,"fig":2,"pear":0}` {
+		t.Fatalf("json.Marshal() = %s, %v", data, err)
+	}
+
+	var doc struct{ Nums *ArenaRBT[int, string] }
+	doc.Nums = NewRBT[int, string]()
+	if err := json.Unmarshal([]byte(`{"Nums": [[3,"c"], [1,"a"], [2,"b"]]}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "c"}
+	for i, r := range doc.Nums.GetAll() {
+		if r.Key != i+1 || r.Val != want[i] {
+			t.Errorf("GetAll()[%v] = %v", i, r)
+		}
+	}
+	if doc.Nums.Size() != 3 {
+		t.Errorf("Size() = %v; want 3", doc.Nums.Size())
+	}
+
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(500) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	data, _ = json.Marshal(src)
+	dst := NewRBT[int, string]()
+	if err := json.Unmarshal(data, dst); err != nil || !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("json round trip: %v", err)
+	}
+}
+
+func TestRange(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for _, k := range rand.Perm(200) {
+		rbt.Put(2*k, strconv.Itoa(2*k))
+	}
+	for _, r := range [][2]int{{-10, -1}, {-10, 0}, {5, 17}, {100, 100}, {101, 101}, {390, 500}, {0, 398}} {
+		var keys []int
+		for p := range rbt.Range(r[0], r[1]) {
+			keys = append(keys, p.Key)
+		}
+		if want := rbt.KeysInOrder(r[0], r[1]); !slices.Equal(keys, want) {
+			t.Errorf("Range(%v, %v) = %v; want %v", r[0], r[1], keys, want)
+		}
+	}
+	n := 0
+	for range rbt.Range(0, 398) {
+		if n++; n == 3 {
+			break
+		}
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(5000) {
+		src.Put(k, strings.Repeat("x", k%50))
+	}
+	src.Put(-1, strings.Repeat("big", 100000))
+
+	// the binary stream is the MarshalBinary format
+	var buf bytes.Buffer
+	n, err := src.WriteTo(&buf, codec.NewBinaryEncoder[int, string](nil, nil))
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("WriteTo() = %v, %v; wrote %v bytes", n, err, buf.Len())
+	}
+	data, _ := src.MarshalBinary()
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("WriteTo() differs from MarshalBinary()")
+	}
+
+	dst, _, err := ReadFrom[int, string](bytes.NewReader(data), codec.NewBinaryDecoder[int, string](nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRbt(t, dst, dst.root)
+	if !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Fatalf("ReadFrom(binary) does not match the source")
+	}
+
+	data[len(data)-5] ^= 1
+	if _, _, err := ReadFrom[int, string](bytes.NewReader(data), codec.NewBinaryDecoder[int, string](nil, nil)); !errors.Is(err, codec.ErrChecksum) {
+		t.Errorf("ReadFrom(corrupt) err = %v; want %v", err, codec.ErrChecksum)
+	}
+	if _, _, err := ReadFrom[int, string](bytes.NewReader(data[:len(data)/2]), codec.NewBinaryDecoder[int, string](nil, nil)); err == nil {
+		t.Errorf("ReadFrom(truncated) err = nil")
+	}
+
+	buf.Reset()
+	if _, err := src.WriteTo(&buf, codec.JSONLinesEncoder[int, string]{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "[-1,\"bigbig") {
+		t.Errorf("JSON lines start %.20q", buf.String())
+	}
+	if dst, _, err = ReadFrom[int, string](&buf, codec.JSONLinesDecoder[int, string]{}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Fatalf("ReadFrom(JSON lines) does not match the source")
+	}
+
+	if _, _, err := ReadFrom[int, string](strings.NewReader("[2,\"b\"]\n[1,\"a\"]\n"), codec.JSONLinesDecoder[int, string]{}); !errors.Is(err, codec.ErrOrder) {
+		t.Errorf("ReadFrom(unsorted) err = %v; want %v", err, codec.ErrOrder)
+	}
+}
+
+// deleted slots go on the free list and are handed out again, so the arena
+// never holds more slots than the most keys the tree has held at once
+func TestFreeList(t *testing.T) {
+	tree := NewRBT[int, int]()
+	most := 0
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(1000)
+		switch rand.Intn(5) {
+		case 0, 1:
+			tree.Put(k, k)
+		case 2:
+			tree.Delete(k)
+		case 3:
+			tree.DeleteMin()
+		case 4:
+			tree.DeleteMax()
+		}
+		most = max(most, tree.Size())
+		if tree.Slots() > most {
+			t.Fatalf("Slots() = %v; most keys held %v", tree.Slots(), most)
+		}
+	}
+	checkRbt(t, tree, tree.root)
+
+	free := 0
+	for x := tree.free; x != 0; x = tree.nodes[x].left {
+		free++
+	}
+	if free+tree.Size() != tree.Slots() {
+		t.Errorf("%v free + %v in use != %v slots", free, tree.Size(), tree.Slots())
+	}
+
+	for tree.Size() > 0 {
+		tree.DeleteMin()
+	}
+	slots := tree.Slots()
+	for i := 0; i < slots; i++ {
+		tree.Put(i, i)
+	}
+	if tree.Slots() != slots {
+		t.Errorf("Slots() = %v after refilling; want %v", tree.Slots(), slots)
+	}
+	checkRbt(t, tree, tree.root)
+}
+
+func TestNewRBTWithCapacity(t *testing.T) {
+	tree := NewRBTWithCapacity[int, int](100)
+	for i := 0; i < 100; i++ {
+		tree.Put(i, i)
+	}
+	if c := cap(tree.nodes); c != 101 {
+		t.Errorf("cap = %v after 100 puts; want 101", c)
+	}
+	checkRbt(t, tree, tree.root)
+}
+
+func TestIsRed(t *testing.T) {
+	tree := NewRBT[int, int]()
+	if tree.IsRed(0) {
+		t.Errorf("IsRed(0) = true; want false")
+	}
+	tree.Put(1, 1)
+	tree.Put(2, 2)
+	// 2 is the black root with 1 as its red left child
+	if tree.IsRed(tree.root) || !tree.IsRed(tree.nodes[tree.root].left) {
+		t.Errorf("IsRed(root) = %v, IsRed(root.left) = %v; want false, true", tree.IsRed(tree.root), tree.IsRed(tree.nodes[tree.root].left))
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	tree := NewRBT[int, string]()
+	for _, k := range rand.Perm(1000) {
+		tree.Put(3*k, strconv.Itoa(3*k))
+	}
+	checkRbt(t, tree, tree.root)
+	for i := 0; i < 1000; i++ {
+		if k, ok := tree.Select(i); !ok || k != 3*i {
+			t.Fatalf("Select(%v) = %v, %v; want %v", i, k, ok, 3*i)
+		}
+		if r := tree.Rank(3 * i); r != i {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i, r, i)
+		}
+		if k, ok := tree.Floor(3*i + 2); !ok || k != 3*i {
+			t.Fatalf("Floor(%v) = %v, %v; want %v", 3*i+2, k, ok, 3*i)
+		}
+		if k, ok := tree.Ceiling(3*i + 1); i < 999 && (!ok || k != 3*i+3) {
+			t.Fatalf("Ceiling(%v) = %v, %v; want %v", 3*i+1, k, ok, 3*i+3)
+		}
+	}
+	if _, ok := tree.Select(1000); ok {
+		t.Errorf("Select(1000) found a key")
+	}
+	if _, ok := tree.Floor(-1); ok {
+		t.Errorf("Floor(-1) found a key")
+	}
+	if n := tree.SizeInOrder(10, 40); n != 10 {
+		t.Errorf("SizeInOrder(10, 40) = %v; want 10", n)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	tree := NewRBT[int, string]()
+	var events []rbt.Event[int, string]
+	tree.OnChange(func(ev rbt.Event[int, string]) {
+		events = append(events, ev)
+	})
+	tree.Put(1, "one")
+	tree.Put(1, "uno")
+	tree.Put(2, "two")
+	tree.Delete(1)
+	tree.Delete(3)
+	tree.DeleteMax()
+	want := []rbt.Event[int, string]{
+		{Kind: rbt.EventPut, Key: 1, New: "one"},
+		{Kind: rbt.EventUpdate, Key: 1, New: "uno", Old: "one"},
+		{Kind: rbt.EventPut, Key: 2, New: "two"},
+		{Kind: rbt.EventDelete, Key: 1, Old: "uno"},
+		{Kind: rbt.EventDelete, Key: 2, Old: "two"},
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v; want %v", events, want)
+	}
+}
+
+// ************ benchmarks against the pointer based LLRB trees ************
+
+var impls = append([]bench.Impl[int]{
+	{Name: "arena", New: func() rbt.RBT[int, int] { return NewRBT[int, int]() }},
+}, bench.LLRB[int]()...)
+
+func BenchmarkGet(b *testing.B) {
+	bench.Random(b, impls, bench.Get[int])
+}
+
+func BenchmarkPut(b *testing.B) {
+	bench.Random(b, impls, bench.Put[int])
+}
+
+// ns/op is one full collection with the tree live and pause-ns/op the
+// stop-the-world time of each. the arena holds no pointers, so the
+// collector does not have to trace its nodes
+func BenchmarkGC(b *testing.B) {
+	bench.Random(b, impls, func(b *testing.B, t rbt.RBT[int, int], keys []int) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		pause, gcs := ms.PauseTotalNs, ms.NumGC
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+		}
+		b.StopTimer()
+		runtime.ReadMemStats(&ms)
+		b.ReportMetric(float64(ms.PauseTotalNs-pause)/float64(max(ms.NumGC-gcs, 1)), "pause-ns/op")
+		runtime.KeepAlive(t)
+	})
+}
+
+// bytes/key is the heap the tree holds on to, measured after building it
+func BenchmarkMemory(b *testing.B) {
+	for _, n := range bench.Sizes() {
+		keys := rand.Perm(n)
+		for _, impl := range impls {
+			b.Run(fmt.Sprintf("%v/n=%v", impl.Name, n), func(b *testing.B) {
+				var ms runtime.MemStats
+				for i := 0; i < b.N; i++ {
+					runtime.GC()
+					runtime.ReadMemStats(&ms)
+					before := int64(ms.HeapAlloc)
+					t := impl.New()
+					for _, k := range keys {
+						t.Put(k, k)
+					}
+					runtime.GC()
+					runtime.ReadMemStats(&ms)
+					// int64 so a heap that shrank reports a negative size rather than wrapping
+					b.ReportMetric(float64(int64(ms.HeapAlloc)-before)/float64(n), "bytes/key")
+					runtime.KeepAlive(t)
+				}
+			})
+		}
+	}
+}
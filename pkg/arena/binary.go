@@ -0,0 +1,34 @@
+package arena
+
+import (
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, and through it gob encoding
+func (t *ArenaRBT[K, V]) MarshalBinary() ([]byte, error) {
+	return codec.MarshalBinary(&t.Codecs, t.Size(), t.Iterator())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. it replaces the
+// contents of the tree, rebuilding it in linear time from the sorted stream.
+func (t *ArenaRBT[K, V]) UnmarshalBinary(data []byte) error {
+	return codec.UnmarshalBinary(&t.Codecs, data, t.load)
+}
+
+// replace the contents of the tree with pairs sorted by key in a fresh
+// arena with no free slots, see codec.BuildLLRB
+func (t *ArenaRBT[K, V]) load(pairs []rbt.KeyValuePair[K, V]) {
+	t.nodes = make([]node[K, V], 1, len(pairs)+1)
+	t.free = 0
+	t.root = codec.BuildLLRB(pairs, func(p rbt.KeyValuePair[K, V], left, right int32, size int, isRed bool) int32 {
+		x := t.alloc(p.Key, p.Val)
+		n := &t.nodes[x]
+		n.left, n.right = left, right
+		n.sc = uint32(size)
+		if isRed {
+			n.sc |= red
+		}
+		return x
+	})
+}
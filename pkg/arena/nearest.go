@@ -0,0 +1,59 @@
+package arena
+
+import (
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Nearest returns an iterator over the k entries whose keys are closest to key,
+// in increasing distance order, smaller key first on a tie. see rbt.Nearest
+func Nearest[K rbt.Number, V any](t *ArenaRBT[K, V], key K, k int) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.Nearest(t.neighbors(), key, k)
+}
+
+// WithinDistance returns an iterator over the entries whose keys are no more
+// than d away from key, in the same order as Nearest. see rbt.WithinDistance
+func WithinDistance[K rbt.Number, V any](t *ArenaRBT[K, V], key K, d K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.WithinDistance(t.neighbors(), key, d)
+}
+
+func (t *ArenaRBT[K, V]) neighbors() rbt.Neighbors[K, V] {
+	pair := func(x int32) (rbt.KeyValuePair[K, V], bool) {
+		if x == 0 {
+			return rbt.KeyValuePair[K, V]{}, false
+		}
+		return rbt.KeyValuePair[K, V]{Key: t.nodes[x].key, Val: t.nodes[x].val}, true
+	}
+	return rbt.Neighbors[K, V]{
+		Floor:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.floor(t.root, k)) },
+		Lower:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.lower(t.root, k)) },
+		Higher: func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.higher(t.root, k)) },
+	}
+}
+
+// lower returns the node with the largest key strictly less than key
+func (t *ArenaRBT[K, V]) lower(x int32, key K) int32 {
+	var best int32
+	for x != 0 {
+		if compare(t.nodes[x].key, key) < 0 {
+			best = x
+			x = t.nodes[x].right
+		} else {
+			x = t.nodes[x].left
+		}
+	}
+	return best
+}
+
+// higher returns the node with the smallest key strictly greater than key
+func (t *ArenaRBT[K, V]) higher(x int32, key K) int32 {
+	var best int32
+	for x != 0 {
+		if compare(t.nodes[x].key, key) > 0 {
+			best = x
+			x = t.nodes[x].left
+		} else {
+			x = t.nodes[x].right
+		}
+	}
+	return best
+}
@@ -0,0 +1,48 @@
+package arena
+
+import (
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// ParallelForEach calls fn for every entry, spreading the work over workers
+// goroutines that each get a contiguous, equal range of ranks found from the
+// subtree sizes. fn is called concurrently. see rbt.ParallelForEach
+func (t *ArenaRBT[K, V]) ParallelForEach(workers int, fn func(K, V)) {
+	rbt.ParallelForEach(t.ranks, t.Size(), workers, fn)
+}
+
+// ParallelReduce folds every entry into a result using workers goroutines,
+// each starting from its own init() and combined in key order.
+// see rbt.ParallelReduce
+func ParallelReduce[K constraints.Ordered, V any, T any](t *ArenaRBT[K, V], workers int, init func() T, accumulate func(T, K, V) T, combine func(T, T) T) T {
+	return rbt.ParallelReduce(t.ranks, t.Size(), workers, init, accumulate, combine)
+}
+
+// ParallelMap calls fn for every entry using workers goroutines and returns
+// the results in key order. see rbt.ParallelMap
+func ParallelMap[K constraints.Ordered, V any, T any](t *ArenaRBT[K, V], workers int, fn func(K, V) T) []T {
+	return rbt.ParallelMap(t.ranks, t.Size(), workers, fn)
+}
+
+// visit the entries whose rank is in [lo, hi), an rbt.Ranks
+func (t *ArenaRBT[K, V]) ranks(lo int, hi int, visit func(K, V)) {
+	t.rangeByRank(t.root, 0, lo, hi, func(x int32) {
+		visit(t.nodes[x].key, t.nodes[x].val)
+	})
+}
+
+// rangeByRank visits, in order, the nodes of the subtree rooted at x whose
+// rank is in [lo, hi). base is the rank of the smallest key in the subtree.
+// Subtrees entirely outside the range are skipped using their size.
+func (t *ArenaRBT[K, V]) rangeByRank(x int32, base int, lo int, hi int, visit func(int32)) {
+	if x == 0 || base >= hi || base+t.size(x) <= lo {
+		return
+	}
+	r := base + t.size(t.nodes[x].left)
+	t.rangeByRank(t.nodes[x].left, base, lo, hi, visit)
+	if r >= lo && r < hi {
+		visit(x)
+	}
+	t.rangeByRank(t.nodes[x].right, r+1, lo, hi, visit)
+}
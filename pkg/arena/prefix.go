@@ -0,0 +1,38 @@
+package arena
+
+import (
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// PrefixScan returns an iterator over the entries whose keys start with prefix,
+// in key order, in O(log n + k). see rbt.PrefixScan
+func PrefixScan[K ~string, V any](t *ArenaRBT[K, V], prefix K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.PrefixScan(t.ascend, prefix)
+}
+
+// LongestPrefixOf returns the longest key in the tree that is a prefix of s.
+// see rbt.LongestPrefixOf
+func LongestPrefixOf[K ~string, V any](t *ArenaRBT[K, V], s K) (K, bool) {
+	return rbt.LongestPrefixOf(t.neighbors().Floor, s)
+}
+
+// ascend iterates in order over the keys >= from, skipping the subtrees below it
+func (t *ArenaRBT[K, V]) ascend(from K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(int32) bool
+		inorder = func(x int32) bool {
+			if x == 0 {
+				return true
+			}
+			n := &t.nodes[x]
+			if from < n.key && !inorder(n.left) {
+				return false
+			}
+			if from <= n.key && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
+				return false
+			}
+			return inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
@@ -0,0 +1,439 @@
+package art
+
+import (
+	"strings"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// an adaptive radix tree, after Leis, Kemper and Neumann, "The Adaptive
+// Radix Tree: ARTful Indexing for Main-Memory Databases", ICDE 2013.
+// a key is looked up one byte at a time instead of by comparing whole keys,
+// so a lookup costs O(len(key)) however many keys the tree holds, and keys
+// that share a long prefix, like paths, do not pay to compare it again at
+// every level. inner nodes come in four sizes, holding up to 4, 16, 48 and
+// 256 children, and grow and shrink with the number of children they have.
+// runs of bytes with only one child are compressed into a node's prefix,
+// and a leaf hangs as soon as its key is the only one below a node.
+//
+// children are in byte order, so iteration is in the same order as
+// comparing strings. a key that is a prefix of another ends at an inner
+// node, in its end leaf, which comes before all of the node's children.
+
+// Key is the set of key types the lookups accept. the tree stores keys as
+// strings, a []byte key is converted once when it is inserted
+type Key interface {
+	~string | ~[]byte
+}
+
+type ArtRBT[V any] struct {
+	root *node[V]
+	size int
+}
+
+func NewRBT[V any]() *ArtRBT[V] {
+	return &ArtRBT[V]{}
+}
+
+func (t *ArtRBT[V]) IsEmpty() bool {
+	return t.root == nil
+}
+
+func (t *ArtRBT[V]) Size() int {
+	return t.size
+}
+
+// get the length of the longest common prefix of a and b
+func commonPrefix[K Key](a string, b K) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+func (t *ArtRBT[V]) Get(key string) (V, bool) {
+	return get(t.root, key)
+}
+
+// GetBytes is Get for a []byte key, without converting it to a string
+func (t *ArtRBT[V]) GetBytes(key []byte) (V, bool) {
+	return get(t.root, key)
+}
+
+func get[V any, K Key](x *node[V], key K) (V, bool) {
+	depth := 0
+	for x != nil {
+		if x.kind == leaf {
+			if x.key == string(key) {
+				return x.val, true
+			}
+			break
+		}
+		if commonPrefix(x.prefix, key[depth:]) < len(x.prefix) {
+			break
+		}
+		depth += len(x.prefix)
+		if depth == len(key) {
+			if x.end != nil {
+				return x.end.val, true
+			}
+			break
+		}
+		x = x.child(key[depth])
+		depth++
+	}
+	var zero V
+	return zero, false
+}
+
+func (t *ArtRBT[V]) Contains(key string) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// insert a key-value pair, replacing the value of an existing key
+func (t *ArtRBT[V]) Put(key string, val V) {
+	if insert(&t.root, key, val, 0) {
+		t.size++
+	}
+}
+
+// PutBytes is Put for a []byte key
+func (t *ArtRBT[V]) PutBytes(key []byte, val V) {
+	t.Put(string(key), val)
+}
+
+// hang the leaf l below x, which has consumed depth bytes of its key:
+// as x's end leaf if nothing is left, otherwise on the next byte
+func (x *node[V]) hang(l *node[V], depth int) {
+	if depth == len(l.key) {
+		x.end = l
+	} else {
+		x.add(l.key[depth], l)
+	}
+}
+
+// insert key below *ref, the first depth bytes of key having been consumed
+// by the edges leading to it. returns true if the key is new
+func insert[V any](ref **node[V], key string, val V, depth int) bool {
+	for {
+		x := *ref
+		if x == nil {
+			*ref = newLeaf(key, val)
+			return true
+		}
+		if x.kind == leaf {
+			if x.key == key {
+				x.val = val
+				return false
+			}
+			// two keys now share this place, put a node over them holding
+			// the bytes they share
+			p := commonPrefix(x.key[depth:], key[depth:])
+			n := newNode4[V](key[depth : depth+p])
+			n.hang(x, depth+p)
+			n.hang(newLeaf(key, val), depth+p)
+			*ref = n
+			return true
+		}
+		if p := commonPrefix(x.prefix, key[depth:]); p < len(x.prefix) {
+			// key leaves the prefix part way, split it there
+			n := newNode4[V](x.prefix[:p])
+			n.add(x.prefix[p], x)
+			x.prefix = x.prefix[p+1:]
+			n.hang(newLeaf(key, val), depth+p)
+			*ref = n
+			return true
+		}
+		depth += len(x.prefix)
+		if depth == len(key) {
+			if x.end != nil {
+				x.end.val = val
+				return false
+			}
+			x.end = newLeaf(key, val)
+			return true
+		}
+		next := x.ref(key[depth])
+		if next == nil {
+			x.add(key[depth], newLeaf(key, val))
+			return true
+		}
+		ref = next
+		depth++
+	}
+}
+
+func (t *ArtRBT[V]) Delete(key string) {
+	if remove(&t.root, key, 0) {
+		t.size--
+	}
+}
+
+// DeleteBytes is Delete for a []byte key
+func (t *ArtRBT[V]) DeleteBytes(key []byte) {
+	if remove(&t.root, key, 0) {
+		t.size--
+	}
+}
+
+// remove key from below *ref, returning true if it was there
+func remove[V any, K Key](ref **node[V], key K, depth int) bool {
+	x := *ref
+	if x == nil {
+		return false
+	}
+	if x.kind == leaf {
+		if x.key == string(key) {
+			*ref = nil
+			return true
+		}
+		return false
+	}
+	if commonPrefix(x.prefix, key[depth:]) < len(x.prefix) {
+		return false
+	}
+	depth += len(x.prefix)
+	if depth == len(key) {
+		if x.end == nil {
+			return false
+		}
+		x.end = nil
+	} else {
+		b := key[depth]
+		next := x.ref(b)
+		if next == nil || !remove(next, key, depth+1) {
+			return false
+		}
+		if *next == nil {
+			x.remove(b)
+		}
+	}
+	collapse(ref)
+	return true
+}
+
+// replace the inner node *ref with what is left in it once it holds a
+// single key or child
+func collapse[V any](ref **node[V]) {
+	x := *ref
+	switch {
+	case x.num == 0:
+		// only the end leaf is left, or nothing
+		*ref = x.end
+	case x.num == 1 && x.end == nil:
+		var b byte
+		var c *node[V]
+		x.each(func(e byte, n *node[V]) bool {
+			b, c = e, n
+			return false
+		})
+		if c.kind != leaf {
+			// fold this node's prefix and the edge into the child's
+			c.prefix = x.prefix + string([]byte{b}) + c.prefix
+		}
+		*ref = c
+	}
+}
+
+func (t *ArtRBT[V]) DeleteMin() {
+	if k, ok := t.Min(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *ArtRBT[V]) DeleteMax() {
+	if k, ok := t.Max(); ok {
+		t.Delete(k)
+	}
+}
+
+// the smallest key, found by following the end leaf or the first child
+func (t *ArtRBT[V]) Min() (string, bool) {
+	x := t.root
+	if x == nil {
+		return "", false
+	}
+	for x.kind != leaf {
+		if x.end != nil {
+			x = x.end
+		} else {
+			x = x.first()
+		}
+	}
+	return x.key, true
+}
+
+// the largest key, found by following the last child
+func (t *ArtRBT[V]) Max() (string, bool) {
+	x := t.root
+	if x == nil {
+		return "", false
+	}
+	for x.kind != leaf {
+		if x.num > 0 {
+			x = x.last()
+		} else {
+			x = x.end
+		}
+	}
+	return x.key, true
+}
+
+// visit the leaves below x in order
+func walk[V any](x *node[V], yield func(rbt.KeyValuePair[string, V]) bool) bool {
+	if x.kind == leaf {
+		return yield(rbt.KeyValuePair[string, V]{Key: x.key, Val: x.val})
+	}
+	if x.end != nil && !yield(rbt.KeyValuePair[string, V]{Key: x.end.key, Val: x.end.val}) {
+		return false
+	}
+	if x.kind == node48 {
+		for _, i := range x.index {
+			if i > 0 && !walk(x.children[i-1], yield) {
+				return false
+			}
+		}
+		return true
+	}
+	// the children of the other kinds are already in edge order
+	for _, c := range x.children {
+		if c != nil && !walk(c, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *ArtRBT[V]) GetAll() []rbt.KeyValuePair[string, V] {
+	pairs := make([]rbt.KeyValuePair[string, V], 0, t.size)
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (t *ArtRBT[V]) Iterator() func(func(rbt.KeyValuePair[string, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[string, V]) bool) {
+		if t.root != nil {
+			walk(t.root, yield)
+		}
+	}
+}
+
+// PrefixScan iterates in order over the keys that start with prefix. it
+// follows prefix down to the node below which every key matches and walks
+// only that subtree
+func (t *ArtRBT[V]) PrefixScan(prefix string) func(func(rbt.KeyValuePair[string, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[string, V]) bool) {
+		x := t.root
+		depth := 0
+		for x != nil {
+			if x.kind == leaf {
+				if strings.HasPrefix(x.key, prefix) {
+					yield(rbt.KeyValuePair[string, V]{Key: x.key, Val: x.val})
+				}
+				return
+			}
+			p := commonPrefix(x.prefix, prefix[depth:])
+			if depth+p == len(prefix) {
+				// prefix runs out inside or at the end of x.prefix
+				walk(x, yield)
+				return
+			}
+			if p < len(x.prefix) {
+				return
+			}
+			depth += len(x.prefix)
+			x = x.child(prefix[depth])
+			depth++
+		}
+	}
+}
+
+// iterate over the keys in [lo..hi] in order. the walk only descends into
+// the children whose edges can lead to keys in the range
+func (t *ArtRBT[V]) Range(lo string, hi string) func(func(rbt.KeyValuePair[string, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[string, V]) bool) {
+		if t.root != nil && lo <= hi {
+			walkRange(t.root, 0, lo, hi, true, true, yield)
+		}
+	}
+}
+
+// visit the leaves below x in [lo..hi]. the path to x spells depth bytes;
+// while atLo the path equals the first depth bytes of lo, so the keys below
+// x may fall short of lo, and likewise atHi for hi. once a bound is off,
+// every key below x is on the right side of it
+func walkRange[V any](x *node[V], depth int, lo string, hi string, atLo bool, atHi bool, yield func(rbt.KeyValuePair[string, V]) bool) bool {
+	if x.kind == leaf {
+		if lo <= x.key && x.key <= hi {
+			return yield(rbt.KeyValuePair[string, V]{Key: x.key, Val: x.val})
+		}
+		return true
+	}
+	if atLo {
+		if c := compareBound(x.prefix, lo[depth:]); c < 0 {
+			return true
+		} else if c > 0 {
+			atLo = false
+		}
+	}
+	if atHi {
+		if c := compareBound(x.prefix, hi[depth:]); c > 0 {
+			return true
+		} else if c < 0 {
+			atHi = false
+		}
+	}
+	depth += len(x.prefix)
+	if !atLo && !atHi {
+		return walk(x, yield)
+	}
+	if x.end != nil && lo <= x.end.key && x.end.key <= hi {
+		if !yield(rbt.KeyValuePair[string, V]{Key: x.end.key, Val: x.end.val}) {
+			return false
+		}
+	}
+	// the path now ends at depth; a key equal to it is the end leaf, done
+	// above, and every child key is longer
+	if atHi && depth >= len(hi) {
+		return true
+	}
+	return x.each(func(b byte, c *node[V]) bool {
+		childLo, childHi := atLo && depth < len(lo), atHi
+		if childLo {
+			if b < lo[depth] {
+				return true
+			}
+			childLo = b == lo[depth]
+		}
+		if childHi {
+			if b > hi[depth] {
+				return false
+			}
+			childHi = b == hi[depth]
+		}
+		if !childLo && !childHi {
+			return walk(c, yield)
+		}
+		return walkRange(c, depth+1, lo, hi, childLo, childHi, yield)
+	})
+}
+
+// compare the keys that continue with prefix against the rest of a bound.
+// 0 if they may fall either side, because prefix matches the start of rest,
+// otherwise the side of the bound they all fall on
+func compareBound(prefix string, rest string) int {
+	if len(prefix) <= len(rest) {
+		return strings.Compare(prefix, rest[:len(prefix)])
+	}
+	// the keys are longer than the bound, if they start with it they are above
+	if c := strings.Compare(prefix[:len(rest)], rest); c != 0 {
+		return c
+	}
+	return 1
+}
@@ -0,0 +1,364 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/avl"
+	"sqirvy.xyz/go-tree-iterator/btree"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func TestEmptyRbt(t *testing.T) {
+	art := NewRBT[int]()
+	if !art.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", art.IsEmpty())
+	}
+	if v, ok := art.Get(""); ok || v != 0 {
+		t.Errorf("Get() = %v, %v; want 0, false", v, ok)
+	}
+	if _, ok := art.Min(); ok {
+		t.Errorf("Min() found a key")
+	}
+}
+
+func TestPutThreeRbt(t *testing.T) {
+	art := NewRBT[string]()
+	art.Put("one", "1")
+	art.Put("two", "2")
+	art.Put("three", "3")
+	for _, k := range []string{"one", "two", "three"} {
+		if !art.Contains(k) {
+			t.Errorf("Contains(%v) == false", k)
+		}
+	}
+	if v, ok := art.Get("t"); ok {
+		t.Errorf("Get(t) = %v", v)
+	}
+	if art.Size() != 3 {
+		t.Errorf("Size() = %v; want 3", art.Size())
+	}
+}
+
+// check the kind of each node fits its children, the edges are in order and
+// the prefixes are compressed. returns the number of keys below x
+func checkNode[V any](t *testing.T, x *node[V], path string) int {
+	if x.kind == leaf {
+		if !strings.HasPrefix(x.key, path) {
+			t.Fatalf("leaf %q below path %q", x.key, path)
+		}
+		return 1
+	}
+	path += x.prefix
+	limits := map[kind][2]int{node4: {1, 4}, node16: {4, 16}, node48: {13, 48}, node256: {38, 256}}
+	if l := limits[x.kind]; x.num < l[0] && !(x.kind == node4 && x.end != nil) || x.num > l[1] {
+		t.Fatalf("node kind %v has %v children", x.kind, x.num)
+	}
+	if x.num+boolInt(x.end != nil) < 2 {
+		t.Fatalf("node at %q has %v children and end %v", path, x.num, x.end != nil)
+	}
+	if (x.kind == node4 || x.kind == node16) && !slices.IsSorted(x.edges[:x.num]) {
+		t.Fatalf("edges %v out of order", x.edges[:x.num])
+	}
+	n := 0
+	if x.end != nil {
+		if x.end.key != path {
+			t.Fatalf("end leaf %q at path %q", x.end.key, path)
+		}
+		n++
+	}
+	num := 0
+	x.each(func(b byte, c *node[V]) bool {
+		num++
+		n += checkNode(t, c, path+string([]byte{b}))
+		return true
+	})
+	if num != x.num {
+		t.Fatalf("node counts %v children; has %v", x.num, num)
+	}
+	return n
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func check[V any](t *testing.T, art *ArtRBT[V]) {
+	n := 0
+	if art.root != nil {
+		n = checkNode(t, art.root, "")
+	}
+	if n != art.Size() {
+		t.Fatalf("%v keys; Size() = %v", n, art.Size())
+	}
+}
+
+// keys over a small alphabet, so that many are prefixes of each other
+func randomKey(r *rand.Rand) string {
+	b := make([]byte, r.Intn(8))
+	for i := range b {
+		b[i] = "abc/"[r.Intn(4)]
+	}
+	return string(b)
+}
+
+func TestRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	art := NewRBT[int]()
+	m := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		k := randomKey(r)
+		switch r.Intn(5) {
+		case 0, 1:
+			art.Put(k, i)
+			m[k] = i
+		case 2:
+			art.Delete(k)
+			delete(m, k)
+		case 3:
+			if lo, ok := art.Min(); ok {
+				delete(m, lo)
+			}
+			art.DeleteMin()
+		case 4:
+			if hi, ok := art.Max(); ok {
+				delete(m, hi)
+			}
+			art.DeleteMax()
+		}
+		if art.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", art.Size(), len(m))
+		}
+		if i%100 == 0 {
+			check(t, art)
+		}
+	}
+	check(t, art)
+	for k, v := range m {
+		if x, ok := art.Get(k); !ok || x != v {
+			t.Errorf("Get(%q) = %v, %v; want %v", k, x, ok, v)
+		}
+	}
+	want := make([]string, 0, len(m))
+	for k := range m {
+		want = append(want, k)
+	}
+	slices.Sort(want)
+	got := make([]string, 0, len(m))
+	for p := range art.Iterator() {
+		got = append(got, p.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %q; want %q", got, want)
+	}
+}
+
+// filling a node to 256 children and emptying it again passes through
+// every kind
+func TestGrowShrink(t *testing.T) {
+	art := NewRBT[int]()
+	kinds := []kind{}
+	for b := 0; b < 256; b++ {
+		art.PutBytes([]byte{'x', byte(b)}, b)
+		if k := art.root.kind; len(kinds) == 0 || kinds[len(kinds)-1] != k {
+			kinds = append(kinds, k)
+		}
+		check(t, art)
+	}
+	if want := []kind{leaf, node4, node16, node48, node256}; !slices.Equal(kinds, want) {
+		t.Errorf("kinds while growing = %v; want %v", kinds, want)
+	}
+	if art.root.prefix != "x" {
+		t.Errorf("root prefix = %q; want x", art.root.prefix)
+	}
+	for b := 0; b < 256; b++ {
+		if v, ok := art.GetBytes([]byte{'x', byte(b)}); !ok || v != b {
+			t.Fatalf("GetBytes(x%v) = %v, %v", b, v, ok)
+		}
+	}
+	kinds = kinds[:0]
+	for b := 255; b > 0; b-- {
+		art.DeleteBytes([]byte{'x', byte(b)})
+		if k := art.root.kind; len(kinds) == 0 || kinds[len(kinds)-1] != k {
+			kinds = append(kinds, k)
+		}
+		check(t, art)
+	}
+	if want := []kind{node256, node48, node16, node4, leaf}; !slices.Equal(kinds, want) {
+		t.Errorf("kinds while shrinking = %v; want %v", kinds, want)
+	}
+	if k, ok := art.Min(); !ok || k != "x\x00" || art.Size() != 1 {
+		t.Errorf("Min() = %q after deleting; Size() = %v", k, art.Size())
+	}
+}
+
+var paths = []string{"", "/", "/usr", "/usr/bin", "/usr/bin/go", "/usr/bin/gofmt", "/usr/lib", "/usrx", "/var", "/var/log"}
+
+func TestPrefixScan(t *testing.T) {
+	art := NewRBT[int]()
+	for _, i := range rand.Perm(len(paths)) {
+		art.Put(paths[i], i)
+	}
+	check(t, art)
+	for _, prefix := range []string{"", "/", "/u", "/usr", "/usr/", "/usr/bin/go", "/usr/bin/gox", "/usrx", "/v", "/w", "x"} {
+		var got []string
+		for p := range art.PrefixScan(prefix) {
+			got = append(got, p.Key)
+		}
+		var want []string
+		for _, p := range paths {
+			if strings.HasPrefix(p, prefix) {
+				want = append(want, p)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("PrefixScan(%q) = %q; want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	art := NewRBT[int]()
+	keys := make([]string, 0)
+	for i := 0; i < 2000; i++ {
+		k := randomKey(r)
+		if !art.Contains(k) {
+			keys = append(keys, k)
+		}
+		art.Put(k, i)
+	}
+	slices.Sort(keys)
+	bounds := append(slices.Clone(paths), "a", "ab", "abc", "b", "c/", "cc", "\xff")
+	for i := 0; i < 200; i++ {
+		bounds = append(bounds, randomKey(r))
+	}
+	for _, lo := range bounds {
+		for _, hi := range bounds[:20] {
+			var got []string
+			for p := range art.Range(lo, hi) {
+				got = append(got, p.Key)
+			}
+			var want []string
+			for _, k := range keys {
+				if lo <= k && k <= hi {
+					want = append(want, k)
+				}
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("Range(%q, %q) = %q; want %q", lo, hi, got, want)
+			}
+		}
+	}
+
+	n := 0
+	for range art.Range("", "\xff") {
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("break after %v keys", n)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	art := NewRBT[int]()
+	key := []byte("/usr/bin")
+	art.PutBytes(key, 1)
+	key[1] = 'x'
+	if _, ok := art.Get("/usr/bin"); !ok {
+		t.Errorf("PutBytes kept a reference to its key")
+	}
+	if v, ok := art.GetBytes([]byte("/usr/bin")); !ok || v != 1 {
+		t.Errorf("GetBytes() = %v, %v", v, ok)
+	}
+	art.DeleteBytes([]byte("/usr/bin"))
+	if !art.IsEmpty() {
+		t.Errorf("DeleteBytes() left %v keys", art.Size())
+	}
+}
+
+// ************ benchmarks on path-like keys ************
+
+// keys like /usr/share/doc/lib/f000123.txt, directories picked from a small
+// set so that many keys share long prefixes
+func pathKeys(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	dirs := []string{"usr", "share", "lib", "local", "bin", "src", "pkg", "doc", "home", "var", "cache", "go"}
+	keys := make([]string, n)
+	var b bytes.Buffer
+	for i := range keys {
+		b.Reset()
+		for d := 2 + r.Intn(4); d > 0; d-- {
+			b.WriteByte('/')
+			b.WriteString(dirs[r.Intn(len(dirs))])
+		}
+		fmt.Fprintf(&b, "/f%07d.txt", i)
+		keys[i] = b.String()
+	}
+	return keys
+}
+
+var impls = append([]bench.Impl[string]{
+	{Name: "art", New: func() rbt.RBT[string, int] { return NewRBT[int]() }},
+}, append(bench.LLRB[string](),
+	bench.Impl[string]{Name: "avl", New: func() rbt.RBT[string, int] { return avl.NewRBT[string, int]() }},
+	bench.Impl[string]{Name: "btree", New: func() rbt.RBT[string, int] { return btree.NewRBT[string, int](btree.DefaultDegree) }},
+)...)
+
+// run op against each implementation filled with n path keys, looking
+// them up in a different order than they were put
+func benchmark(b *testing.B, op bench.Op[string]) {
+	for _, n := range []int{1e4, 1e5, 1e6} {
+		keys := pathKeys(n)
+		shuffled := slices.Clone(keys)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		bench.Run(b, impls, bench.Workload[string]{Name: fmt.Sprintf("n=%v", n), Fill: keys, Keys: shuffled}, op)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchmark(b, bench.Get[string])
+}
+
+func BenchmarkPut(b *testing.B) {
+	benchmark(b, bench.Put[string])
+}
+
+// ns/op is per key visited
+func BenchmarkIterate(b *testing.B) {
+	benchmark(b, bench.Iterate[string])
+}
+
+// a prefix that selects about one key in 150
+func BenchmarkPrefixScan(b *testing.B) {
+	keys := pathKeys(1e5)
+	art := NewRBT[int]()
+	llrb := cp.NewRBT[string, int]()
+	for _, k := range keys {
+		art.Put(k, 0)
+		llrb.Put(k, 0)
+	}
+	b.Run("art", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for range art.PrefixScan("/usr/lib/go") {
+			}
+		}
+	})
+	b.Run("copilot", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for range cp.PrefixScan(llrb, "/usr/lib/go") {
+			}
+		}
+	})
+}
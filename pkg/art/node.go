@@ -0,0 +1,244 @@
+package art
+
+import "bytes"
+
+type kind uint8
+
+const (
+	leaf kind = iota
+	node4
+	node16
+	node48
+	node256
+)
+
+// one struct holds every kind of node so a node can grow or shrink in place
+// without its parent having to swap in a new pointer. the fields a lookup
+// reads come first, to share a cache line. a leaf uses key and val, an
+// inner node the rest
+type node[V any] struct {
+	kind     kind
+	num      int         // number of children
+	prefix   string      // bytes every key below shares after the edge into the node
+	edges    [16]byte    // node4, node16: the first num edge bytes in order, parallel to children
+	children []*node[V]  // node256: indexed by the edge byte
+	index    *[256]uint8 // node48: 1 + the slot in children of each edge byte, 0 if none
+	end      *node[V]    // the leaf whose key ends right after prefix
+
+	key string // leaf: the whole key
+	val V      // leaf
+}
+
+func newLeaf[V any](key string, val V) *node[V] {
+	return &node[V]{kind: leaf, key: key, val: val}
+}
+
+func newNode4[V any](prefix string) *node[V] {
+	return &node[V]{kind: node4, prefix: prefix, children: make([]*node[V], 0, 4)}
+}
+
+// get the child on the edge b, nil if there is none
+func (x *node[V]) child(b byte) *node[V] {
+	if p := x.ref(b); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// get the slot holding the child on the edge b, nil if there is none
+func (x *node[V]) ref(b byte) **node[V] {
+	switch x.kind {
+	case node4, node16:
+		if i := bytes.IndexByte(x.edges[:x.num], b); i >= 0 {
+			return &x.children[i]
+		}
+	case node48:
+		if i := x.index[b]; i > 0 {
+			return &x.children[i-1]
+		}
+	case node256:
+		if x.children[b] != nil {
+			return &x.children[b]
+		}
+	}
+	return nil
+}
+
+// add a child on the edge b, which must be free, growing the node if it is full
+func (x *node[V]) add(b byte, c *node[V]) {
+	switch x.kind {
+	case node4, node16:
+		if x.num == cap(x.children) {
+			x.grow()
+			x.add(b, c)
+			return
+		}
+		i := 0
+		for i < x.num && x.edges[i] < b {
+			i++
+		}
+		x.children = append(x.children, nil)
+		copy(x.edges[i+1:x.num+1], x.edges[i:x.num])
+		copy(x.children[i+1:], x.children[i:])
+		x.edges[i] = b
+		x.children[i] = c
+	case node48:
+		if x.num == 48 {
+			x.grow()
+			x.add(b, c)
+			return
+		}
+		i := 0
+		for x.children[i] != nil {
+			i++
+		}
+		x.children[i] = c
+		x.index[b] = uint8(i + 1)
+	case node256:
+		x.children[b] = c
+	}
+	x.num++
+}
+
+// remove the child on the edge b, shrinking the node once it is sparse
+func (x *node[V]) remove(b byte) {
+	switch x.kind {
+	case node4, node16:
+		i := bytes.IndexByte(x.edges[:x.num], b)
+		copy(x.edges[i:x.num-1], x.edges[i+1:x.num])
+		copy(x.children[i:], x.children[i+1:])
+		x.children[x.num-1] = nil
+		x.children = x.children[:x.num-1]
+	case node48:
+		x.children[x.index[b]-1] = nil
+		x.index[b] = 0
+	case node256:
+		x.children[b] = nil
+	}
+	x.num--
+	// shrink below the size that made the node grow, so a node on the
+	// boundary does not flip back and forth
+	switch {
+	case x.kind == node16 && x.num <= 3,
+		x.kind == node48 && x.num <= 12,
+		x.kind == node256 && x.num <= 37:
+		x.shrink()
+	}
+}
+
+// move the children into the next larger kind
+func (x *node[V]) grow() {
+	switch x.kind {
+	case node4:
+		x.kind = node16
+		x.children = append(make([]*node[V], 0, 16), x.children...)
+	case node16:
+		x.kind = node48
+		x.index = new([256]uint8)
+		children := make([]*node[V], 48)
+		for i, b := range x.edges {
+			children[i] = x.children[i]
+			x.index[b] = uint8(i + 1)
+		}
+		x.children = children
+	case node48:
+		x.kind = node256
+		children := make([]*node[V], 256)
+		for b, i := range x.index {
+			if i > 0 {
+				children[b] = x.children[i-1]
+			}
+		}
+		x.index = nil
+		x.children = children
+	}
+}
+
+// move the children into the next smaller kind
+func (x *node[V]) shrink() {
+	switch x.kind {
+	case node16:
+		x.kind = node4
+		x.children = append(make([]*node[V], 0, 4), x.children...)
+	case node48:
+		x.kind = node16
+		children := make([]*node[V], 0, 16)
+		for b, i := range x.index {
+			if i > 0 {
+				x.edges[len(children)] = byte(b)
+				children = append(children, x.children[i-1])
+			}
+		}
+		x.index = nil
+		x.children = children
+	case node256:
+		x.kind = node48
+		x.index = new([256]uint8)
+		children := make([]*node[V], 48)
+		i := 0
+		for b, c := range x.children {
+			if c != nil {
+				children[i] = c
+				x.index[b] = uint8(i + 1)
+				i++
+			}
+		}
+		x.children = children
+	}
+}
+
+// call fn on each child in edge order until it returns false
+func (x *node[V]) each(fn func(b byte, c *node[V]) bool) bool {
+	switch x.kind {
+	case node4, node16:
+		for i, c := range x.children {
+			if !fn(x.edges[i], c) {
+				return false
+			}
+		}
+	case node48:
+		for b, i := range x.index {
+			if i > 0 && !fn(byte(b), x.children[i-1]) {
+				return false
+			}
+		}
+	case node256:
+		for b, c := range x.children {
+			if c != nil && !fn(byte(b), c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// get the child on the smallest edge
+func (x *node[V]) first() *node[V] {
+	var first *node[V]
+	x.each(func(_ byte, c *node[V]) bool {
+		first = c
+		return false
+	})
+	return first
+}
+
+// get the child on the largest edge
+func (x *node[V]) last() *node[V] {
+	switch x.kind {
+	case node4, node16:
+		return x.children[x.num-1]
+	case node48:
+		for b := 255; b >= 0; b-- {
+			if i := x.index[b]; i > 0 {
+				return x.children[i-1]
+			}
+		}
+	case node256:
+		for b := 255; b >= 0; b-- {
+			if c := x.children[b]; c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package avl
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func TestEmptyRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+func TestPutOneRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	if rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want false", rbt.IsEmpty())
+	}
+
+	v, ok := rbt.Get(1)
+	if !ok {
+		t.Errorf("Get(1) = %v; want 'one'", v)
+	}
+	if v != "one" {
+		t.Errorf("Get(1) = %v; want 'one'", v)
+	}
+}
+
+func TestPutThreeRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+	if rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want false", rbt.IsEmpty())
+	}
+}
+
+func TestContains3Rbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+
+	if x, ok := rbt.Get(1); !ok {
+		t.Errorf("Get(1) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(2); !ok {
+		t.Errorf("Get(2) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(3); !ok {
+		t.Errorf("Get(3) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(4); ok {
+		t.Errorf("Get(4) == %v; want false", x)
+	}
+}
+
+// test that the keys are returned in order
+func TestContainsKeysRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+
+	v, ok := rbt.Get(1)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'one'", 1, v)
+	}
+	if v != "one" {
+		t.Errorf("Get(%v) = %v; want 'one'", 1, v)
+	}
+
+	v, ok = rbt.Get(2)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'two'", 2, v)
+	}
+	if v != "two" {
+		t.Errorf("Get(%v) = %v; want 'two'", 2, v)
+	}
+
+	v, ok = rbt.Get(3)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'three'", 3, v)
+	}
+	if v != "three" {
+		t.Errorf("Get(%v) = %v; want 'three'", 3, v)
+	}
+
+}
+
+// create a map of random keys and values
+func TestRandomKeystRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	keys := make([]int, 100)
+	values := make([]string, 100)
+	for i := range keys {
+		keys[i] = rand.Intn(100)
+		values[i] = strconv.Itoa(keys[i])
+	}
+
+	for i := 0; i < len(keys); i++ {
+		rbt.Put(keys[i], values[i])
+	}
+
+	for i := 0; i < len(keys); i++ {
+		v, ok := rbt.Get(keys[i])
+		if !ok {
+			t.Errorf("Get(%v) = %v; want %v", keys[i], v, values[i])
+		}
+		if v != values[i] {
+			t.Errorf("Get(%v) = %v; want %v", keys[i], v, values[i])
+		}
+	}
+}
+
+// test the Iterator with a large number of random keys
+func TestIteratorRandom(t *testing.T) {
+	rbt := NewRBT[int, string]()
+
+	m := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		k := rand.Intn(100)
+		v := strconv.Itoa(k)
+		m[k] = v
+	}
+
+	// iterate over the map m
+	t.Log("--- random keys")
+	for k, v := range m {
+		t.Log(k, v)
+		rbt.Put(k, v)
+	}
+
+	k := -1
+	for r := range rbt.Iterator() {
+		if r.Key < k {
+			t.Errorf("Out of order(%v) = %v; ", k, r.Key)
+		}
+		t.Log(r.Key, r.Val)
+		k = r.Key
+	}
+}
+
+// check the AVL balance, heights and subtree sizes, return the height
+func checkAvl[K constraints.Ordered, V any](t *testing.T, x *Node[K, V]) int {
+	if x == nil {
+		return -1
+	}
+	lh, rh := checkAvl(t, x.left), checkAvl(t, x.right)
+	if lh-rh > 1 || rh-lh > 1 {
+		t.Fatalf("subtree heights %v and %v at %v", lh, rh, x.key)
+	}
+	if x.height != 1+max(lh, rh) {
+		t.Fatalf("height = %v at %v; want %v", x.height, x.key, 1+max(lh, rh))
+	}
+	if x.size != 1+size(x.left)+size(x.right) {
+		t.Fatalf("size = %v at %v; want %v", x.size, x.key, 1+size(x.left)+size(x.right))
+	}
+	return x.height
+}
+
+func TestDeleteRandom(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	m := make(map[int]string)
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(500)
+		switch rand.Intn(5) {
+		case 0, 1:
+			rbt.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			rbt.Delete(k)
+			delete(m, k)
+		case 3:
+			if lo, ok := rbt.Min(); ok {
+				delete(m, lo)
+			}
+			rbt.DeleteMin()
+		case 4:
+			if hi, ok := rbt.Max(); ok {
+				delete(m, hi)
+			}
+			rbt.DeleteMax()
+		}
+		if rbt.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", rbt.Size(), len(m))
+		}
+		if i%100 == 0 {
+			checkAvl(t, rbt.root)
+		}
+	}
+	checkAvl(t, rbt.root)
+	for k, v := range m {
+		if x, ok := rbt.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for _, k := range rand.Perm(100) {
+		rbt.Put(3*k, strconv.Itoa(3*k))
+	}
+	for i := 0; i < 100; i++ {
+		if k, ok := rbt.Select(i); !ok || k != 3*i {
+			t.Errorf("Select(%v) = %v, %v; want %v", i, k, ok, 3*i)
+		}
+		if r := rbt.Rank(3 * i); r != i {
+			t.Errorf("Rank(%v) = %v; want %v", 3*i, r, i)
+		}
+		if r := rbt.Rank(3*i + 1); r != i+1 {
+			t.Errorf("Rank(%v) = %v; want %v", 3*i+1, r, i+1)
+		}
+		if k, ok := rbt.Floor(3*i + 2); !ok || k != 3*i {
+			t.Errorf("Floor(%v) = %v, %v; want %v", 3*i+2, k, ok, 3*i)
+		}
+		if k, ok := rbt.Ceiling(3*i - 1); !ok || k != 3*i {
+			t.Errorf("Ceiling(%v) = %v, %v; want %v", 3*i-1, k, ok, 3*i)
+		}
+	}
+	if _, ok := rbt.Select(100); ok {
+		t.Errorf("Select(100) found a key")
+	}
+	if _, ok := rbt.Floor(-1); ok {
+		t.Errorf("Floor(-1) found a key")
+	}
+	if _, ok := rbt.Ceiling(298); ok {
+		t.Errorf("Ceiling(298) found a key")
+	}
+
+	var keys []int
+	for r := range rbt.Range(10, 20) {
+		keys = append(keys, r.Key)
+	}
+	if !slices.Equal(keys, []int{12, 15, 18}) {
+		t.Errorf("Range(10, 20) = %v", keys)
+	}
+
+	// a sorted insert, the worst case for an unbalanced tree, stays within 1.44 lg n
+	sorted := NewRBT[int, int]()
+	for i := 0; i < 1<<16; i++ {
+		sorted.Put(i, i)
+	}
+	if h := sorted.Height(); h > 23 {
+		t.Errorf("Height() = %v after 2^16 sorted inserts", h)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		src := NewRBT[int, string]()
+		for _, k := range rand.Perm(n) {
+			src.Put(k, strconv.Itoa(k))
+		}
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := NewRBT[int, string]()
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		checkAvl(t, dst.root)
+		if !slices.Equal(dst.GetAll(), src.GetAll()) {
+			t.Fatalf("n = %v: GetAll() = %v; want %v", n, dst.GetAll(), src.GetAll())
+		}
+	}
+
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(500) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	data, _ := json.Marshal(src)
+	dst := NewRBT[int, string]()
+	if err := json.Unmarshal(data, dst); err != nil || !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("json round trip: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf, codec.NewBinaryEncoder[int, string](nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	dst, _, err := ReadFrom[int, string](&buf, codec.NewBinaryDecoder[int, string](nil, nil))
+	if err != nil || !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("stream round trip: %v", err)
+	}
+}
+
+// ************ benchmarks against the LLRB trees ************
+
+var impls = append([]bench.Impl[int]{
+	{Name: "avl", New: func() rbt.RBT[int, int] { return NewRBT[int, int]() }},
+}, bench.LLRB[int]()...)
+
+// lookup heavy workload, where the stricter AVL balance should pay off
+func BenchmarkGet(b *testing.B) {
+	bench.Random(b, impls, bench.Get[int])
+}
+
+func BenchmarkPut(b *testing.B) {
+	bench.Random(b, impls, bench.Put[int])
+}
+
+// ns/op is per key visited
+func BenchmarkIterate(b *testing.B) {
+	bench.Random(b, impls, bench.Iterate[int])
+}
@@ -0,0 +1,26 @@
+package avl
+
+import (
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, and through it gob encoding
+func (t *AvlRBT[K, V]) MarshalBinary() ([]byte, error) {
+	return codec.MarshalBinary(&t.Codecs, t.Size(), t.Iterator())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. it replaces the
+// contents of the tree, rebuilding it in linear time from the sorted stream.
+func (t *AvlRBT[K, V]) UnmarshalBinary(data []byte) error {
+	return codec.UnmarshalBinary(&t.Codecs, data, t.load)
+}
+
+// replace the contents of the tree with pairs sorted by key, see codec.BuildBalanced
+func (t *AvlRBT[K, V]) load(pairs []rbt.KeyValuePair[K, V]) {
+	t.root = codec.BuildBalanced(pairs, func(p rbt.KeyValuePair[K, V], left, right *Node[K, V]) *Node[K, V] {
+		x := &Node[K, V]{key: p.Key, val: p.Val, left: left, right: right}
+		update(x)
+		return x
+	})
+}
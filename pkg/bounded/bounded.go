@@ -0,0 +1,93 @@
+package bounded
+
+import (
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Evict selects which end of the tree is dropped when it is over capacity
+type Evict int
+
+const (
+	EvictMin Evict = iota // drop the smallest key, keeping the largest (top-K)
+	EvictMax              // drop the largest key, keeping the smallest (bottom-K)
+)
+
+// BoundedRBT is a tree that holds at most capacity entries. When a Put grows
+// the tree past capacity the smallest or largest entry is removed, which may
+// be the entry that was just inserted.
+type BoundedRBT[K constraints.Ordered, V any] struct {
+	tree     rbt.OrderedRBT[K, V]
+	capacity int
+	evict    Evict
+	onEvict  func(K, V)
+}
+
+// create a bounded tree on top of an existing tree. onEvict, if not nil,
+// is called with each entry as it is removed
+func NewRBT[K constraints.Ordered, V any](tree rbt.OrderedRBT[K, V], capacity int, evict Evict, onEvict func(K, V)) *BoundedRBT[K, V] {
+	b := &BoundedRBT[K, V]{
+		tree:     tree,
+		capacity: capacity,
+		evict:    evict,
+		onEvict:  onEvict,
+	}
+	b.trim()
+	return b
+}
+
+// get the maximum number of entries
+func (b *BoundedRBT[K, V]) Capacity() int {
+	return b.capacity
+}
+
+// get the number of entries
+func (b *BoundedRBT[K, V]) Size() int {
+	return b.tree.Size()
+}
+
+// insert a key-value pair, evicting entries while the tree is over capacity
+func (b *BoundedRBT[K, V]) Put(key K, val V) {
+	b.tree.Put(key, val)
+	b.trim()
+}
+
+// evict entries until the tree is within capacity. the size comes from
+// the root's subtree count so the check is O(1)
+func (b *BoundedRBT[K, V]) trim() {
+	for b.tree.Size() > b.capacity && !b.tree.IsEmpty() {
+		var key K
+		if b.evict == EvictMin {
+			key, _ = b.tree.Min()
+		} else {
+			key, _ = b.tree.Max()
+		}
+		val, _ := b.tree.Get(key)
+
+		if b.evict == EvictMin {
+			b.tree.DeleteMin()
+		} else {
+			b.tree.DeleteMax()
+		}
+		if b.onEvict != nil {
+			b.onEvict(key, val)
+		}
+	}
+}
+
+func (b *BoundedRBT[K, V]) Get(key K) (V, bool) {
+	return b.tree.Get(key)
+}
+
+func (b *BoundedRBT[K, V]) IsEmpty() bool {
+	return b.tree.IsEmpty()
+}
+
+func (b *BoundedRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	return b.tree.GetAll()
+}
+
+func (b *BoundedRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return b.tree.Iterator()
+}
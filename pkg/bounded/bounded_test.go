@@ -0,0 +1,94 @@
+package bounded
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/avl"
+	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+var trees = []struct {
+	name string
+	new  func() rbt.OrderedRBT[int, int]
+}{
+	{"copilot", func() rbt.OrderedRBT[int, int] { return cp.NewRBT[int, int]() }},
+	{"gemini", func() rbt.OrderedRBT[int, int] { return gm.NewRBT[int, int]() }},
+	{"chatgpt", func() rbt.OrderedRBT[int, int] { return ch.NewRBT[int, int]() }},
+	{"avl", func() rbt.OrderedRBT[int, int] { return avl.NewRBT[int, int]() }},
+}
+
+func keys(b *BoundedRBT[int, int]) []int {
+	k := make([]int, 0)
+	for r := range b.Iterator() {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+// test that EvictMin keeps the largest keys and reports every eviction
+func TestTopK(t *testing.T) {
+	for _, tc := range trees {
+		evicted := make(map[int]int)
+		b := NewRBT(tc.new(), 10, EvictMin, func(k int, v int) {
+			evicted[k] = v
+		})
+
+		all := rand.Perm(1000)
+		for _, k := range all {
+			b.Put(k, -k)
+		}
+
+		if b.Size() != 10 {
+			t.Errorf("%v: Size() = %v; want 10", tc.name, b.Size())
+		}
+		sort.Ints(all)
+		if got, want := keys(b), all[990:]; !slices.Equal(got, want) {
+			t.Errorf("%v: keys = %v; want %v", tc.name, got, want)
+		}
+		if len(evicted) != 990 {
+			t.Errorf("%v: %v evictions; want 990", tc.name, len(evicted))
+		}
+		for k, v := range evicted {
+			if k >= 990 || v != -k {
+				t.Errorf("%v: evicted %v=%v", tc.name, k, v)
+			}
+		}
+	}
+}
+
+// test that EvictMax keeps the smallest keys
+func TestBottomK(t *testing.T) {
+	for _, tc := range trees {
+		b := NewRBT(tc.new(), 5, EvictMax, nil)
+		for _, k := range rand.Perm(100) {
+			b.Put(k, k)
+		}
+		if got, want := keys(b), []int{0, 1, 2, 3, 4}; !slices.Equal(got, want) {
+			t.Errorf("%v: keys = %v; want %v", tc.name, got, want)
+		}
+	}
+}
+
+// test that updating an existing key does not evict
+func TestUpdateAtCapacity(t *testing.T) {
+	for _, tc := range trees {
+		n := 0
+		b := NewRBT(tc.new(), 3, EvictMin, func(int, int) { n++ })
+		b.Put(1, 1)
+		b.Put(2, 2)
+		b.Put(3, 3)
+		b.Put(1, 10)
+		if n != 0 {
+			t.Errorf("%v: %v evictions after update; want 0", tc.name, n)
+		}
+		if v, ok := b.Get(1); !ok || v != 10 {
+			t.Errorf("%v: Get(1) = %v, %v; want 10, true", tc.name, v, ok)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package btree
+
+import (
+	"slices"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// a B-tree as in CLRS chapter 18. every node except the root holds between
+// t-1 and 2t-1 keys, where t is the minimum degree, so a node spans a few
+// cache lines and a lookup touches O(log_t n) nodes instead of O(lg n).
+// insert splits full nodes and delete tops up minimal nodes on the way
+// down, so neither has to walk back up. each node also counts the keys in
+// its subtree for Select and Rank.
+
+// DefaultDegree is a minimum degree that suits small keys
+const DefaultDegree = 32
+
+type node[K constraints.Ordered, V any] struct {
+	keys     []K
+	vals     []V
+	children []*node[K, V] // nil in a leaf
+	size     int           // keys in the subtree
+}
+
+func (x *node[K, V]) leaf() bool {
+	return x.children == nil
+}
+
+type BTreeRBT[K constraints.Ordered, V any] struct {
+	root *node[K, V]
+	t    int
+}
+
+// create a tree with minimum degree t, values below 2 are raised to 2
+func NewRBT[K constraints.Ordered, V any](t int) *BTreeRBT[K, V] {
+	t = max(t, 2)
+	return &BTreeRBT[K, V]{root: &node[K, V]{}, t: t}
+}
+
+func (b *BTreeRBT[K, V]) IsEmpty() bool {
+	return b.root.size == 0
+}
+
+func (b *BTreeRBT[K, V]) Size() int {
+	return b.root.size
+}
+
+// the minimum degree of the tree
+func (b *BTreeRBT[K, V]) Degree() int {
+	return b.t
+}
+
+// the number of levels, 0 when the tree is empty
+func (b *BTreeRBT[K, V]) Height() int {
+	if b.IsEmpty() {
+		return 0
+	}
+	h := 1
+	for x := b.root; !x.leaf(); x = x.children[0] {
+		h++
+	}
+	return h
+}
+
+func (b *BTreeRBT[K, V]) Get(key K) (V, bool) {
+	x := b.root
+	for {
+		i, found := slices.BinarySearch(x.keys, key)
+		if found {
+			return x.vals[i], true
+		}
+		if x.leaf() {
+			var zero V
+			return zero, false
+		}
+		x = x.children[i]
+	}
+}
+
+func (b *BTreeRBT[K, V]) Contains(key K) bool {
+	_, ok := b.Get(key)
+	return ok
+}
+
+// insert a key-value pair, replacing the value of an existing key
+func (b *BTreeRBT[K, V]) Put(key K, val V) {
+	if len(b.root.keys) == 2*b.t-1 {
+		root := &node[K, V]{children: []*node[K, V]{b.root}, size: b.root.size}
+		b.split(root, 0)
+		b.root = root
+	}
+	b.insert(b.root, key, val)
+}
+
+// insert into the subtree of x, which is not full. returns true if the key is new
+func (b *BTreeRBT[K, V]) insert(x *node[K, V], key K, val V) bool {
+	i, found := slices.BinarySearch(x.keys, key)
+	if found {
+		x.vals[i] = val
+		return false
+	}
+	if x.leaf() {
+		x.keys = slices.Insert(x.keys, i, key)
+		x.vals = slices.Insert(x.vals, i, val)
+		x.size++
+		return true
+	}
+	if len(x.children[i].keys) == 2*b.t-1 {
+		b.split(x, i)
+		if key == x.keys[i] {
+			x.vals[i] = val
+			return false
+		}
+		if key > x.keys[i] {
+			i++
+		}
+	}
+	if b.insert(x.children[i], key, val) {
+		x.size++
+		return true
+	}
+	return false
+}
+
+// split the full child i of x around its median key, which moves up into x
+func (b *BTreeRBT[K, V]) split(x *node[K, V], i int) {
+	t := b.t
+	y := x.children[i]
+	z := &node[K, V]{
+		keys: append([]K(nil), y.keys[t:]...),
+		vals: append([]V(nil), y.vals[t:]...),
+	}
+	if !y.leaf() {
+		z.children = append([]*node[K, V](nil), y.children[t:]...)
+		clear(y.children[t:])
+		y.children = y.children[:t]
+	}
+	x.keys = slices.Insert(x.keys, i, y.keys[t-1])
+	x.vals = slices.Insert(x.vals, i, y.vals[t-1])
+	x.children = slices.Insert(x.children, i+1, z)
+
+	// clear the moved entries so the old slots do not keep them alive
+	clear(y.keys[t-1:])
+	clear(y.vals[t-1:])
+	y.keys, y.vals = y.keys[:t-1], y.vals[:t-1]
+	resize(y)
+	resize(z)
+}
+
+// recompute the size of x from its keys and children
+func resize[K constraints.Ordered, V any](x *node[K, V]) {
+	x.size = len(x.keys)
+	for _, c := range x.children {
+		x.size += c.size
+	}
+}
+
+// remove a key from the tree
+func (b *BTreeRBT[K, V]) Delete(key K) {
+	b.delete(b.root, key)
+	if len(b.root.keys) == 0 && !b.root.leaf() {
+		b.root = b.root.children[0]
+	}
+}
+
+func (b *BTreeRBT[K, V]) DeleteMin() {
+	if k, ok := b.Min(); ok {
+		b.Delete(k)
+	}
+}
+
+func (b *BTreeRBT[K, V]) DeleteMax() {
+	if k, ok := b.Max(); ok {
+		b.Delete(k)
+	}
+}
+
+// remove key from the subtree of x, which has at least t keys unless it
+// is the root. returns true if the key was found
+func (b *BTreeRBT[K, V]) delete(x *node[K, V], key K) bool {
+	i, found := slices.BinarySearch(x.keys, key)
+	if x.leaf() {
+		if !found {
+			return false
+		}
+		x.keys = slices.Delete(x.keys, i, i+1)
+		x.vals = slices.Delete(x.vals, i, i+1)
+		x.size--
+		return true
+	}
+
+	if found {
+		if y := x.children[i]; len(y.keys) >= b.t {
+			// replace the key with its predecessor and delete that instead
+			p := y
+			for !p.leaf() {
+				p = p.children[len(p.children)-1]
+			}
+			x.keys[i], x.vals[i] = p.keys[len(p.keys)-1], p.vals[len(p.vals)-1]
+			b.delete(y, x.keys[i])
+		} else if z := x.children[i+1]; len(z.keys) >= b.t {
+			// or with its successor
+			s := z
+			for !s.leaf() {
+				s = s.children[0]
+			}
+			x.keys[i], x.vals[i] = s.keys[0], s.vals[0]
+			b.delete(z, x.keys[i])
+		} else {
+			b.merge(x, i)
+			b.delete(y, key)
+		}
+		x.size--
+		return true
+	}
+
+	if len(x.children[i].keys) < b.t {
+		i = b.fill(x, i)
+	}
+	if b.delete(x.children[i], key) {
+		x.size--
+		return true
+	}
+	return false
+}
+
+// give child i of x at least t keys, borrowing from a sibling or merging
+// with one. returns the index of the child that now covers the same keys
+func (b *BTreeRBT[K, V]) fill(x *node[K, V], i int) int {
+	c := x.children[i]
+	if i > 0 && len(x.children[i-1].keys) >= b.t {
+		// rotate the last key of the left sibling through x
+		l := x.children[i-1]
+		c.keys = slices.Insert(c.keys, 0, x.keys[i-1])
+		c.vals = slices.Insert(c.vals, 0, x.vals[i-1])
+		last := len(l.keys) - 1
+		x.keys[i-1], x.vals[i-1] = l.keys[last], l.vals[last]
+		l.keys, l.vals = slices.Delete(l.keys, last, last+1), slices.Delete(l.vals, last, last+1)
+		moved := 1
+		if !l.leaf() {
+			child := l.children[len(l.children)-1]
+			l.children = slices.Delete(l.children, len(l.children)-1, len(l.children))
+			c.children = slices.Insert(c.children, 0, child)
+			moved += child.size
+		}
+		c.size += moved
+		l.size -= moved
+		return i
+	}
+	if i < len(x.keys) && len(x.children[i+1].keys) >= b.t {
+		// rotate the first key of the right sibling through x
+		r := x.children[i+1]
+		c.keys = append(c.keys, x.keys[i])
+		c.vals = append(c.vals, x.vals[i])
+		x.keys[i], x.vals[i] = r.keys[0], r.vals[0]
+		r.keys, r.vals = slices.Delete(r.keys, 0, 1), slices.Delete(r.vals, 0, 1)
+		moved := 1
+		if !r.leaf() {
+			child := r.children[0]
+			r.children = slices.Delete(r.children, 0, 1)
+			c.children = append(c.children, child)
+			moved += child.size
+		}
+		c.size += moved
+		r.size -= moved
+		return i
+	}
+	if i < len(x.keys) {
+		b.merge(x, i)
+		return i
+	}
+	b.merge(x, i-1)
+	return i - 1
+}
+
+// merge child i+1 of x and the key between them into child i
+func (b *BTreeRBT[K, V]) merge(x *node[K, V], i int) {
+	y, z := x.children[i], x.children[i+1]
+	y.keys = append(append(y.keys, x.keys[i]), z.keys...)
+	y.vals = append(append(y.vals, x.vals[i]), z.vals...)
+	y.children = append(y.children, z.children...)
+	y.size += 1 + z.size
+	x.keys = slices.Delete(x.keys, i, i+1)
+	x.vals = slices.Delete(x.vals, i, i+1)
+	x.children = slices.Delete(x.children, i+1, i+2)
+}
+
+// ************ Ordered Symbol Table Functions ***********
+
+func (b *BTreeRBT[K, V]) Min() (K, bool) {
+	if b.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	x := b.root
+	for !x.leaf() {
+		x = x.children[0]
+	}
+	return x.keys[0], true
+}
+
+func (b *BTreeRBT[K, V]) Max() (K, bool) {
+	if b.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	x := b.root
+	for !x.leaf() {
+		x = x.children[len(x.children)-1]
+	}
+	return x.keys[len(x.keys)-1], true
+}
+
+// the key of rank k, the k+1th smallest key
+func (b *BTreeRBT[K, V]) Select(k int) (K, bool) {
+	if k < 0 || k >= b.Size() {
+		var zero K
+		return zero, false
+	}
+	x := b.root
+	for {
+		if x.leaf() {
+			return x.keys[k], true
+		}
+		for i, c := range x.children {
+			if k < c.size {
+				x = c
+				break
+			}
+			k -= c.size
+			if k == 0 && i < len(x.keys) {
+				return x.keys[i], true
+			}
+			k--
+		}
+	}
+}
+
+// the number of keys less than key
+func (b *BTreeRBT[K, V]) Rank(key K) int {
+	r := 0
+	x := b.root
+	for {
+		i, found := slices.BinarySearch(x.keys, key)
+		r += i
+		if x.leaf() {
+			return r
+		}
+		for _, c := range x.children[:i] {
+			r += c.size
+		}
+		if found {
+			return r + x.children[i].size
+		}
+		x = x.children[i]
+	}
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (b *BTreeRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var walk func(*node[K, V]) bool
+		walk = func(x *node[K, V]) bool {
+			i, _ := slices.BinarySearch(x.keys, lo)
+			for ; i <= len(x.keys); i++ {
+				if !x.leaf() && !walk(x.children[i]) {
+					return false
+				}
+				if i == len(x.keys) {
+					break
+				}
+				if x.keys[i] > hi {
+					return false
+				}
+				if !yield(rbt.KeyValuePair[K, V]{Key: x.keys[i], Val: x.vals[i]}) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(b.root)
+	}
+}
+
+func (b *BTreeRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, b.Size())
+	for r := range b.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (b *BTreeRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var walk func(*node[K, V]) bool
+		walk = func(x *node[K, V]) bool {
+			for i := range x.keys {
+				if !x.leaf() && !walk(x.children[i]) {
+					return false
+				}
+				if !yield(rbt.KeyValuePair[K, V]{Key: x.keys[i], Val: x.vals[i]}) {
+					return false
+				}
+			}
+			return x.leaf() || walk(x.children[len(x.keys)])
+		}
+		walk(b.root)
+	}
+}
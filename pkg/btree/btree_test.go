@@ -0,0 +1,187 @@
+package btree
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// test the Iterator with a large number of random keys
+func TestIteratorRandom(t *testing.T) {
+	rbt := NewRBT[int, string](2)
+
+	m := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		k := rand.Intn(100)
+		v := strconv.Itoa(k)
+		m[k] = v
+	}
+
+	// iterate over the map m
+	t.Log("--- random keys")
+	for k, v := range m {
+		t.Log(k, v)
+		rbt.Put(k, v)
+	}
+
+	k := -1
+	for r := range rbt.Iterator() {
+		if r.Key < k {
+			t.Errorf("Out of order(%v) = %v; ", k, r.Key)
+		}
+		t.Log(r.Key, r.Val)
+		k = r.Key
+	}
+}
+
+// check key counts, key order, subtree sizes and that every leaf is at
+// the same depth. returns the depth of the leaves below x
+func checkNode[K constraints.Ordered, V any](t *testing.T, b *BTreeRBT[K, V], x *node[K, V], root bool) int {
+	if !root && (len(x.keys) < b.t-1 || len(x.keys) > 2*b.t-1) {
+		t.Fatalf("node has %v keys with degree %v", len(x.keys), b.t)
+	}
+	if len(x.vals) != len(x.keys) || !slices.IsSorted(x.keys) {
+		t.Fatalf("bad node keys %v", x.keys)
+	}
+	size := len(x.keys)
+	if x.leaf() {
+		if x.size != size {
+			t.Fatalf("leaf size = %v; want %v", x.size, size)
+		}
+		return 0
+	}
+	if len(x.children) != len(x.keys)+1 {
+		t.Fatalf("%v children for %v keys", len(x.children), len(x.keys))
+	}
+	depth := -1
+	for i, c := range x.children {
+		if i > 0 && c.keys[0] <= x.keys[i-1] || i < len(x.keys) && c.keys[len(c.keys)-1] >= x.keys[i] {
+			t.Fatalf("child %v out of order with %v", c.keys, x.keys)
+		}
+		d := checkNode(t, b, c, false)
+		if depth >= 0 && d != depth {
+			t.Fatalf("leaves at depths %v and %v", depth, d)
+		}
+		depth = d
+		size += c.size
+	}
+	if x.size != size {
+		t.Fatalf("size = %v; want %v", x.size, size)
+	}
+	return depth + 1
+}
+
+func TestDeleteRandom(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		tree := NewRBT[int, string](degree)
+		m := make(map[int]string)
+		for i := 0; i < 20000; i++ {
+			k := rand.Intn(1000)
+			switch rand.Intn(5) {
+			case 0, 1:
+				tree.Put(k, strconv.Itoa(k))
+				m[k] = strconv.Itoa(k)
+			case 2:
+				tree.Delete(k)
+				delete(m, k)
+			case 3:
+				if lo, ok := tree.Min(); ok {
+					delete(m, lo)
+				}
+				tree.DeleteMin()
+			case 4:
+				if hi, ok := tree.Max(); ok {
+					delete(m, hi)
+				}
+				tree.DeleteMax()
+			}
+			if tree.Size() != len(m) {
+				t.Fatalf("degree %v: Size() = %v; want %v", degree, tree.Size(), len(m))
+			}
+			if i%100 == 0 {
+				checkNode(t, tree, tree.root, true)
+			}
+		}
+		checkNode(t, tree, tree.root, true)
+		for k, v := range m {
+			if x, ok := tree.Get(k); !ok || x != v {
+				t.Errorf("degree %v: Get(%v) = %v; want %v", degree, k, x, v)
+			}
+		}
+		for tree.Size() > 0 {
+			tree.DeleteMin()
+		}
+		if !tree.IsEmpty() || tree.Height() != 0 {
+			t.Errorf("degree %v: not empty after deleting every key", degree)
+		}
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	tree := NewRBT[int, string](3)
+	for _, k := range rand.Perm(1000) {
+		tree.Put(3*k, strconv.Itoa(3*k))
+	}
+	for i := 0; i < 1000; i++ {
+		if k, ok := tree.Select(i); !ok || k != 3*i {
+			t.Fatalf("Select(%v) = %v, %v; want %v", i, k, ok, 3*i)
+		}
+		if r := tree.Rank(3 * i); r != i {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i, r, i)
+		}
+		if r := tree.Rank(3*i + 1); r != i+1 {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i+1, r, i+1)
+		}
+	}
+	if _, ok := tree.Select(1000); ok {
+		t.Errorf("Select(1000) found a key")
+	}
+
+	for _, r := range [][2]int{{-5, -1}, {0, 0}, {10, 40}, {2990, 3100}, {-1, 3000}} {
+		var keys []int
+		for p := range tree.Range(r[0], r[1]) {
+			keys = append(keys, p.Key)
+		}
+		var want []int
+		for k := (max(r[0], 0) + 2) / 3 * 3; k <= r[1] && k < 3000; k += 3 {
+			want = append(want, k)
+		}
+		if !slices.Equal(keys, want) {
+			t.Errorf("Range(%v, %v) = %v; want %v", r[0], r[1], keys, want)
+		}
+	}
+
+	n := 0
+	for range tree.Iterator() {
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("break after %v keys", n)
+	}
+}
+
+// ************ benchmarks against the LLRB trees ************
+
+var impls = append([]bench.Impl[int]{
+	{Name: "btree", New: func() rbt.RBT[int, int] { return NewRBT[int, int](DefaultDegree) }},
+}, bench.LLRB[int]()...)
+
+func BenchmarkGet(b *testing.B) {
+	bench.Random(b, impls, bench.Get[int])
+}
+
+func BenchmarkPut(b *testing.B) {
+	bench.Random(b, impls, bench.Put[int])
+}
+
+// ns/op is per key visited
+func BenchmarkIterate(b *testing.B) {
+	bench.Random(b, impls, bench.Iterate[int])
+}
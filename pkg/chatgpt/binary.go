@@ -0,0 +1,24 @@
+package chatgpt
+
+import (
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, and through it gob encoding
+func (t *ChatGptRBT[K, V]) MarshalBinary() ([]byte, error) {
+	return codec.MarshalBinary(&t.Codecs, t.Size(), t.Iterator())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. it replaces the
+// contents of the tree, rebuilding it in linear time from the sorted stream.
+func (t *ChatGptRBT[K, V]) UnmarshalBinary(data []byte) error {
+	return codec.UnmarshalBinary(&t.Codecs, data, t.load)
+}
+
+// replace the contents of the tree with pairs sorted by key, see codec.BuildLLRB
+func (t *ChatGptRBT[K, V]) load(pairs []rbt.KeyValuePair[K, V]) {
+	t.root = codec.BuildLLRB(pairs, func(p rbt.KeyValuePair[K, V], left, right *Node[K, V], size int, red bool) *Node[K, V] {
+		return &Node[K, V]{key: p.Key, value: p.Val, left: left, right: right, size: size, color: red}
+	})
+}
@@ -2,6 +2,7 @@ package chatgpt
 
 import (
 	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
 	"sqirvy.xyz/go-tree-iterator/rbt"
 )
 
@@ -28,6 +29,8 @@ type Node[K constraints.Ordered, V any] struct {
 
 // ChatGptRBT represents a red-black binary search tree.
 type ChatGptRBT[K constraints.Ordered, V any] struct {
+	codec.Codecs[K, V] // key and value codecs for MarshalBinary
+
 	root *Node[K, V]
 }
 
@@ -93,9 +96,9 @@ func RotateRight[K constraints.Ordered, V any](h *Node[K, V]) *Node[K, V] {
 
 // FlipColors flips the colors of a node and its two children.
 func FlipColors[K constraints.Ordered, V any](h *Node[K, V]) {
-	h.color = Red
-	h.left.color = Black
-	h.right.color = Black
+	h.color = !h.color
+	h.left.color = !h.left.color
+	h.right.color = !h.right.color
 }
 
 // Put inserts the specified key-value pair into the tree, overwriting the old value with the new value if the tree already contains the specified key.
@@ -304,6 +307,37 @@ func Min[K constraints.Ordered, V any](h *Node[K, V]) *Node[K, V] {
 	return h
 }
 
+// Max returns the node with the maximum key.
+func Max[K constraints.Ordered, V any](h *Node[K, V]) *Node[K, V] {
+	for h.right != nil {
+		h = h.right
+	}
+	return h
+}
+
+// Size returns the number of key-value pairs in the tree.
+func (t *ChatGptRBT[K, V]) Size() int {
+	return Size(t.root)
+}
+
+// Min returns the smallest key in the tree.
+func (t *ChatGptRBT[K, V]) Min() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	return Min(t.root).key, true
+}
+
+// Max returns the largest key in the tree.
+func (t *ChatGptRBT[K, V]) Max() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	return Max(t.root).key, true
+}
+
 // chatgpt fix: add GetAll function
 func (bst *ChatGptRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
 	pairs := make([]rbt.KeyValuePair[K, V], 0)
@@ -323,16 +357,34 @@ func (bst *ChatGptRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
 // chatgpt fix: add range over function Iterator
 func (t *ChatGptRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
 	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
-		var inorder func(*Node[K, V])
-		inorder = func(n *Node[K, V]) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.value}) &&
+				inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (t *ChatGptRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
 			if n == nil {
-				return
+				return true
+			}
+			if lo < n.key && !inorder(n.left) {
+				return false
 			}
-			inorder(n.left)
-			if !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.value}) {
-				return
+			if lo <= n.key && n.key <= hi && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.value}) {
+				return false
 			}
-			inorder(n.right)
+			return hi <= n.key || inorder(n.right)
 		}
 		inorder(t.root)
 	}
@@ -1,9 +1,16 @@
 package chatgpt
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
 	"math/rand"
+	"slices"
 	"strconv"
 	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
 )
 
 func TestEmptyRbt(t *testing.T) {
@@ -151,3 +158,264 @@ func TestIteratorRandom(t *testing.T) {
 		k = r.Key
 	}
 }
+
+// test that Nearest yields keys in distance order with ties going to the smaller key
+func TestNearest(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for _, k := range []int{1, 4, 6, 10, 14, 20} {
+		rbt.Put(k, strconv.Itoa(k))
+	}
+
+	want := []int{10, 6, 14, 4, 1}
+	got := make([]int, 0)
+	for r := range Nearest(rbt, 10, 5) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(10, 5) = %v; want %v", got, want)
+	}
+
+	// probe between keys, 4 and 6 are both 1 away from 5
+	want = []int{4, 6, 1}
+	got = got[:0]
+	for r := range Nearest(rbt, 5, 3) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(5, 3) = %v; want %v", got, want)
+	}
+
+	// k larger than the tree yields everything
+	got = got[:0]
+	for r := range Nearest(rbt, 100, 10) {
+		got = append(got, r.Key)
+	}
+	want = []int{20, 14, 10, 6, 4, 1}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(100, 10) = %v; want %v", got, want)
+	}
+}
+
+// test WithinDistance with float keys
+func TestWithinDistance(t *testing.T) {
+	rbt := NewRBT[float64, string]()
+	for _, k := range []float64{0.5, 1.0, 1.25, 2.0, 3.5} {
+		rbt.Put(k, strconv.FormatFloat(k, 'f', -1, 64))
+	}
+
+	want := []float64{1.25, 1.0, 2.0}
+	got := make([]float64, 0)
+	for r := range WithinDistance(rbt, 1.5, 0.5) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(1.5, 0.5) = %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	for r := range WithinDistance(rbt, 10, 1) {
+		got = append(got, r.Key)
+	}
+	if len(got) != 0 {
+		t.Errorf("WithinDistance(10, 1) = %v; want []", got)
+	}
+}
+
+// test keys at the ends of int64, which are further apart than an int64 holds
+func TestNearestLimits(t *testing.T) {
+	rbt := NewRBT[int64, string]()
+	for _, k := range []int64{math.MinInt64, -1, 0, math.MaxInt64} {
+		rbt.Put(k, strconv.FormatInt(k, 10))
+	}
+
+	want := []int64{math.MaxInt64, 0, -1, math.MinInt64}
+	got := make([]int64, 0)
+	for r := range Nearest(rbt, math.MaxInt64, 4) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Nearest(MaxInt64, 4) = %v; want %v", got, want)
+	}
+
+	// -1 is 2^63 away, further than any int64 distance
+	want = want[:2]
+	got = got[:0]
+	for r := range WithinDistance(rbt, math.MaxInt64, math.MaxInt64) {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(MaxInt64, MaxInt64) = %v; want %v", got, want)
+	}
+}
+
+// test PrefixScan and LongestPrefixOf on path-like keys
+func TestPrefix(t *testing.T) {
+	rbt := NewRBT[string, int]()
+	paths := []string{"/", "/usr", "/usr/bin", "/usr/bin/go", "/usr/lib", "/usrx", "/var", "/var/log"}
+	for i, p := range paths {
+		rbt.Put(p, i)
+	}
+
+	want := []string{"/usr/bin", "/usr/bin/go", "/usr/lib"}
+	got := make([]string, 0)
+	for r := range PrefixScan(rbt, "/usr/") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("PrefixScan(/usr/) = %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	for r := range PrefixScan(rbt, "") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, paths) {
+		t.Errorf("PrefixScan() = %v; want %v", got, paths)
+	}
+
+	tests := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"/usr/bin/gofmt", "/usr/bin/go", true},
+		{"/usr/bin", "/usr/bin", true},
+		{"/usr/local/bin", "/usr", true},
+		{"/usrx/y", "/usrx", true},
+		{"/tmp", "/", true},
+		{"tmp", "", false},
+	}
+	for _, tc := range tests {
+		k, ok := LongestPrefixOf(rbt, tc.s)
+		if k != tc.want || ok != tc.ok {
+			t.Errorf("LongestPrefixOf(%v) = %v, %v; want %v, %v", tc.s, k, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// test DeleteMin and DeleteMax against a sorted slice of random keys
+func TestDeleteMinMax(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	keys := rand.Perm(1000)
+	for _, k := range keys {
+		rbt.Put(k, strconv.Itoa(k))
+	}
+	slices.Sort(keys)
+
+	for len(keys) > 0 {
+		if rand.Intn(2) == 0 {
+			if k, ok := rbt.Min(); !ok || k != keys[0] {
+				t.Fatalf("Min() = %v, %v; want %v", k, ok, keys[0])
+			}
+			rbt.DeleteMin()
+			keys = keys[1:]
+		} else {
+			if k, ok := rbt.Max(); !ok || k != keys[len(keys)-1] {
+				t.Fatalf("Max() = %v, %v; want %v", k, ok, keys[len(keys)-1])
+			}
+			rbt.DeleteMax()
+			keys = keys[:len(keys)-1]
+		}
+		if rbt.Size() != len(keys) {
+			t.Fatalf("Size() = %v; want %v", rbt.Size(), len(keys))
+		}
+	}
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		src := NewRBT[int, string]()
+		for _, k := range rand.Perm(n) {
+			src.Put(k, strconv.Itoa(k))
+		}
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewRBT[int, string]()
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		gdst := NewRBT[int, string]()
+		if err := gob.NewDecoder(&buf).Decode(gdst); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(dst.GetAll(), src.GetAll()) || !slices.Equal(gdst.GetAll(), src.GetAll()) {
+			t.Fatalf("n = %v: GetAll() = %v; want %v", n, dst.GetAll(), src.GetAll())
+		}
+
+		// the rebuilt tree must stay balanced under further updates
+		dst.Put(n, "new")
+		dst.DeleteMin()
+		dst.DeleteMax()
+		if dst.Size() != max(n-1, 0) {
+			t.Fatalf("n = %v: Size() = %v after updates", n, dst.Size())
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	words := NewRBT[string, int]()
+	for i, w := range []string{"pear", "apple", "fig"} {
+		words.Put(w, i)
+	}
+	data, err := json.Marshal(words)
+	if err != nil || string(data) != `{"apple":1,"fig":2,"pear":0}` {
+		t.Fatalf("json.Marshal() = %s, %v", data, err)
+	}
+
+	var doc struct{ Nums *ChatGptRBT[int, string] }
+	doc.Nums = NewRBT[int, string]()
+	if err := json.Unmarshal([]byte(`{"Nums": [[3,"c"], [1,"a"], [2,"b"]]}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "c"}
+	for i, r := range doc.Nums.GetAll() {
+		if r.Key != i+1 || r.Val != want[i] {
+			t.Errorf("GetAll()[%v] = %v", i, r)
+		}
+	}
+	if doc.Nums.Size() != 3 {
+		t.Errorf("Size() = %v; want 3", doc.Nums.Size())
+	}
+
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(500) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	data, _ = json.Marshal(src)
+	dst := NewRBT[int, string]()
+	if err := json.Unmarshal(data, dst); err != nil || !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("json round trip: %v", err)
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(1000) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf, codec.NewBinaryEncoder[int, string](nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	dst, _, err := ReadFrom[int, string](&buf, codec.NewBinaryDecoder[int, string](nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Fatalf("ReadFrom() does not match the source")
+	}
+	dst.DeleteMin()
+	if dst.Size() != 999 {
+		t.Errorf("Size() = %v; want 999", dst.Size())
+	}
+}
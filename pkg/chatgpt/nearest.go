@@ -0,0 +1,59 @@
+package chatgpt
+
+import (
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Nearest returns an iterator over the k entries whose keys are closest to key,
+// in increasing distance order, smaller key first on a tie. see rbt.Nearest
+func Nearest[K rbt.Number, V any](t *ChatGptRBT[K, V], key K, k int) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.Nearest(t.neighbors(), key, k)
+}
+
+// WithinDistance returns an iterator over the entries whose keys are no more
+// than d away from key, in the same order as Nearest. see rbt.WithinDistance
+func WithinDistance[K rbt.Number, V any](t *ChatGptRBT[K, V], key K, d K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.WithinDistance(t.neighbors(), key, d)
+}
+
+func (t *ChatGptRBT[K, V]) neighbors() rbt.Neighbors[K, V] {
+	pair := func(x *Node[K, V]) (rbt.KeyValuePair[K, V], bool) {
+		if x == nil {
+			return rbt.KeyValuePair[K, V]{}, false
+		}
+		return rbt.KeyValuePair[K, V]{Key: x.key, Val: x.value}, true
+	}
+	return rbt.Neighbors[K, V]{
+		Floor:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.below(k, true)) },
+		Lower:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.below(k, false)) },
+		Higher: func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(t.above(k)) },
+	}
+}
+
+// below returns the node with the largest key less than key, or equal to it if orEqual
+func (t *ChatGptRBT[K, V]) below(key K, orEqual bool) *Node[K, V] {
+	var best *Node[K, V]
+	for x := t.root; x != nil; {
+		if x.key < key || (orEqual && x.key == key) {
+			best = x
+			x = x.right
+		} else {
+			x = x.left
+		}
+	}
+	return best
+}
+
+// above returns the node with the smallest key greater than key
+func (t *ChatGptRBT[K, V]) above(key K) *Node[K, V] {
+	var best *Node[K, V]
+	for x := t.root; x != nil; {
+		if x.key > key {
+			best = x
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return best
+}
@@ -0,0 +1,413 @@
+package clrs
+
+import (
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// a classic red-black tree from CLRS chapter 13, unlike the left leaning
+// ports in copilot, gemini and chatgpt. nodes keep parent pointers, so
+// insert and delete fix the tree up iteratively on the way back to the
+// root, and an in-order walk steps from node to successor in O(1)
+// amortized time without a stack.
+//
+// leaves and the parent of the root are a per tree sentinel node, as in
+// CLRS, which keeps the delete fixup free of nil checks.
+
+const (
+	red   = true
+	black = false
+)
+
+type Node[K constraints.Ordered, V any] struct {
+	key                 K
+	val                 V
+	color               bool
+	left, right, parent *Node[K, V]
+}
+
+type ClrsRBT[K constraints.Ordered, V any] struct {
+	root      *Node[K, V]
+	sentinel  *Node[K, V] // stands in for every leaf and the root's parent, always black
+	size      int
+	rotations int
+}
+
+func NewRBT[K constraints.Ordered, V any]() *ClrsRBT[K, V] {
+	sentinel := &Node[K, V]{color: black}
+	return &ClrsRBT[K, V]{root: sentinel, sentinel: sentinel}
+}
+
+func (t *ClrsRBT[K, V]) IsEmpty() bool {
+	return t.root == t.sentinel
+}
+
+func (t *ClrsRBT[K, V]) Size() int {
+	return t.size
+}
+
+// the number of rotations done since the tree was created
+func (t *ClrsRBT[K, V]) Rotations() int {
+	return t.rotations
+}
+
+// find the node holding key, or the sentinel
+func (t *ClrsRBT[K, V]) search(key K) *Node[K, V] {
+	x := t.root
+	for x != t.sentinel && key != x.key {
+		if key < x.key {
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return x
+}
+
+func (t *ClrsRBT[K, V]) Get(key K) (V, bool) {
+	x := t.search(key)
+	if x == t.sentinel {
+		var zero V
+		return zero, false
+	}
+	return x.val, true
+}
+
+func (t *ClrsRBT[K, V]) Contains(key K) bool {
+	return t.search(key) != t.sentinel
+}
+
+// insert a key-value pair, replacing the value of an existing key. RB-INSERT
+func (t *ClrsRBT[K, V]) Put(key K, val V) {
+	y := t.sentinel
+	x := t.root
+	for x != t.sentinel {
+		y = x
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			x = x.right
+		} else {
+			x.val = val
+			return
+		}
+	}
+
+	z := &Node[K, V]{key: key, val: val, color: red, left: t.sentinel, right: t.sentinel, parent: y}
+	if y == t.sentinel {
+		t.root = z
+	} else if key < y.key {
+		y.left = z
+	} else {
+		y.right = z
+	}
+	t.size++
+	t.insertFixup(z)
+}
+
+// RB-INSERT-FIXUP, z is red and may have a red parent
+func (t *ClrsRBT[K, V]) insertFixup(z *Node[K, V]) {
+	for z.parent.color == red {
+		if z.parent == z.parent.parent.left {
+			y := z.parent.parent.right
+			if y.color == red {
+				z.parent.color = black
+				y.color = black
+				z.parent.parent.color = red
+				z = z.parent.parent
+			} else {
+				if z == z.parent.right {
+					z = z.parent
+					t.rotateLeft(z)
+				}
+				z.parent.color = black
+				z.parent.parent.color = red
+				t.rotateRight(z.parent.parent)
+			}
+		} else {
+			y := z.parent.parent.left
+			if y.color == red {
+				z.parent.color = black
+				y.color = black
+				z.parent.parent.color = red
+				z = z.parent.parent
+			} else {
+				if z == z.parent.left {
+					z = z.parent
+					t.rotateRight(z)
+				}
+				z.parent.color = black
+				z.parent.parent.color = red
+				t.rotateLeft(z.parent.parent)
+			}
+		}
+	}
+	t.root.color = black
+}
+
+// remove a key from the tree. RB-DELETE
+func (t *ClrsRBT[K, V]) Delete(key K) {
+	if z := t.search(key); z != t.sentinel {
+		t.delete(z)
+	}
+}
+
+func (t *ClrsRBT[K, V]) DeleteMin() {
+	if !t.IsEmpty() {
+		t.delete(t.min(t.root))
+	}
+}
+
+func (t *ClrsRBT[K, V]) DeleteMax() {
+	if !t.IsEmpty() {
+		t.delete(t.max(t.root))
+	}
+}
+
+func (t *ClrsRBT[K, V]) delete(z *Node[K, V]) {
+	y := z
+	yColor := y.color
+	var x *Node[K, V]
+	if z.left == t.sentinel {
+		x = z.right
+		t.transplant(z, z.right)
+	} else if z.right == t.sentinel {
+		x = z.left
+		t.transplant(z, z.left)
+	} else {
+		y = t.min(z.right)
+		yColor = y.color
+		x = y.right
+		if y.parent == z {
+			x.parent = y // x may be the sentinel
+		} else {
+			t.transplant(y, y.right)
+			y.right = z.right
+			y.right.parent = y
+		}
+		t.transplant(z, y)
+		y.left = z.left
+		y.left.parent = y
+		y.color = z.color
+	}
+	t.size--
+	if yColor == black {
+		t.deleteFixup(x)
+	}
+	// drop references so a removed node does not keep the tree alive
+	z.left, z.right, z.parent = nil, nil, nil
+}
+
+// replace the subtree rooted at u with the one rooted at v. RB-TRANSPLANT
+func (t *ClrsRBT[K, V]) transplant(u, v *Node[K, V]) {
+	if u.parent == t.sentinel {
+		t.root = v
+	} else if u == u.parent.left {
+		u.parent.left = v
+	} else {
+		u.parent.right = v
+	}
+	v.parent = u.parent
+}
+
+// RB-DELETE-FIXUP, x carries an extra black
+func (t *ClrsRBT[K, V]) deleteFixup(x *Node[K, V]) {
+	for x != t.root && x.color == black {
+		if x == x.parent.left {
+			w := x.parent.right
+			if w.color == red {
+				w.color = black
+				x.parent.color = red
+				t.rotateLeft(x.parent)
+				w = x.parent.right
+			}
+			if w.left.color == black && w.right.color == black {
+				w.color = red
+				x = x.parent
+			} else {
+				if w.right.color == black {
+					w.left.color = black
+					w.color = red
+					t.rotateRight(w)
+					w = x.parent.right
+				}
+				w.color = x.parent.color
+				x.parent.color = black
+				w.right.color = black
+				t.rotateLeft(x.parent)
+				x = t.root
+			}
+		} else {
+			w := x.parent.left
+			if w.color == red {
+				w.color = black
+				x.parent.color = red
+				t.rotateRight(x.parent)
+				w = x.parent.left
+			}
+			if w.right.color == black && w.left.color == black {
+				w.color = red
+				x = x.parent
+			} else {
+				if w.left.color == black {
+					w.right.color = black
+					w.color = red
+					t.rotateLeft(w)
+					w = x.parent.left
+				}
+				w.color = x.parent.color
+				x.parent.color = black
+				w.left.color = black
+				t.rotateRight(x.parent)
+				x = t.root
+			}
+		}
+	}
+	x.color = black
+	t.sentinel.parent = nil
+}
+
+// ************ RBT helper functions ************
+
+func (t *ClrsRBT[K, V]) rotateLeft(x *Node[K, V]) {
+	t.rotations++
+	y := x.right
+	x.right = y.left
+	if y.left != t.sentinel {
+		y.left.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == t.sentinel {
+		t.root = y
+	} else if x == x.parent.left {
+		x.parent.left = y
+	} else {
+		x.parent.right = y
+	}
+	y.left = x
+	x.parent = y
+}
+
+func (t *ClrsRBT[K, V]) rotateRight(x *Node[K, V]) {
+	t.rotations++
+	y := x.left
+	x.left = y.right
+	if y.right != t.sentinel {
+		y.right.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == t.sentinel {
+		t.root = y
+	} else if x == x.parent.right {
+		x.parent.right = y
+	} else {
+		x.parent.left = y
+	}
+	y.right = x
+	x.parent = y
+}
+
+func (t *ClrsRBT[K, V]) min(x *Node[K, V]) *Node[K, V] {
+	for x.left != t.sentinel {
+		x = x.left
+	}
+	return x
+}
+
+func (t *ClrsRBT[K, V]) max(x *Node[K, V]) *Node[K, V] {
+	for x.right != t.sentinel {
+		x = x.right
+	}
+	return x
+}
+
+// the node after x in key order, or the sentinel. walking the whole tree
+// this way crosses each edge twice, so a step is O(1) amortized
+func (t *ClrsRBT[K, V]) successor(x *Node[K, V]) *Node[K, V] {
+	if x.right != t.sentinel {
+		return t.min(x.right)
+	}
+	y := x.parent
+	for y != t.sentinel && x == y.right {
+		x = y
+		y = y.parent
+	}
+	return y
+}
+
+// the first node with a key >= key, or the sentinel
+func (t *ClrsRBT[K, V]) ceiling(key K) *Node[K, V] {
+	best := t.sentinel
+	for x := t.root; x != t.sentinel; {
+		if key <= x.key {
+			best = x
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return best
+}
+
+// ************ Ordered Symbol Table Functions ***********
+
+func (t *ClrsRBT[K, V]) Min() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.min(t.root).key, true
+}
+
+func (t *ClrsRBT[K, V]) Max() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.max(t.root).key, true
+}
+
+// the number of nodes on the longest path from the root to a leaf, 0 when empty
+func (t *ClrsRBT[K, V]) Height() int {
+	var height func(*Node[K, V]) int
+	height = func(x *Node[K, V]) int {
+		if x == t.sentinel {
+			return 0
+		}
+		return 1 + max(height(x.left), height(x.right))
+	}
+	return height(t.root)
+}
+
+// iterate over the keys in [lo..hi] in order by walking successors
+func (t *ClrsRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for x := t.ceiling(lo); x != t.sentinel && x.key <= hi; x = t.successor(x) {
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+		}
+	}
+}
+
+func (t *ClrsRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, t.size)
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+// iterate in order by walking successors, no recursion and no stack
+func (t *ClrsRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		if t.IsEmpty() {
+			return
+		}
+		for x := t.min(t.root); x != t.sentinel; x = t.successor(x) {
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package clrs
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// test the Iterator with a large number of random keys
+func TestIteratorRandom(t *testing.T) {
+	rbt := NewRBT[int, string]()
+
+	m := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		k := rand.Intn(100)
+		v := strconv.Itoa(k)
+		m[k] = v
+	}
+
+	// iterate over the map m
+	t.Log("--- random keys")
+	for k, v := range m {
+		t.Log(k, v)
+		rbt.Put(k, v)
+	}
+
+	k := -1
+	for r := range rbt.Iterator() {
+		if r.Key < k {
+			t.Errorf("Out of order(%v) = %v; ", k, r.Key)
+		}
+		t.Log(r.Key, r.Val)
+		k = r.Key
+	}
+}
+
+// check the red-black properties, parent links and key order, return the black height
+func checkRbt[K constraints.Ordered, V any](t *testing.T, tree *ClrsRBT[K, V], x *Node[K, V]) int {
+	if x == tree.sentinel {
+		return 1
+	}
+	if x.left != tree.sentinel && (x.left.parent != x || x.left.key >= x.key) {
+		t.Fatalf("bad left child at %v", x.key)
+	}
+	if x.right != tree.sentinel && (x.right.parent != x || x.right.key <= x.key) {
+		t.Fatalf("bad right child at %v", x.key)
+	}
+	if x.color == red && (x.left.color == red || x.right.color == red) {
+		t.Fatalf("red node %v has a red child", x.key)
+	}
+	lh, rh := checkRbt(t, tree, x.left), checkRbt(t, tree, x.right)
+	if lh != rh {
+		t.Fatalf("black heights %v and %v differ at %v", lh, rh, x.key)
+	}
+	if x.color == black {
+		lh++
+	}
+	return lh
+}
+
+func check[K constraints.Ordered, V any](t *testing.T, tree *ClrsRBT[K, V]) {
+	t.Helper()
+	if tree.root.color != black || tree.sentinel.color != black {
+		t.Fatalf("root or sentinel is red")
+	}
+	if tree.root != tree.sentinel && tree.root.parent != tree.sentinel {
+		t.Fatalf("root has a parent")
+	}
+	checkRbt(t, tree, tree.root)
+}
+
+func TestDeleteRandom(t *testing.T) {
+	tree := NewRBT[int, string]()
+	m := make(map[int]string)
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(500)
+		switch rand.Intn(5) {
+		case 0, 1:
+			tree.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			tree.Delete(k)
+			delete(m, k)
+		case 3:
+			if lo, ok := tree.Min(); ok {
+				delete(m, lo)
+			}
+			tree.DeleteMin()
+		case 4:
+			if hi, ok := tree.Max(); ok {
+				delete(m, hi)
+			}
+			tree.DeleteMax()
+		}
+		if tree.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", tree.Size(), len(m))
+		}
+		if i%100 == 0 {
+			check(t, tree)
+		}
+	}
+	check(t, tree)
+	for k, v := range m {
+		if x, ok := tree.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+	got := tree.GetAll()
+	if len(got) != len(m) || !slices.IsSortedFunc(got, func(a, b rbt.KeyValuePair[int, string]) int { return a.Key - b.Key }) {
+		t.Errorf("GetAll() is not the sorted contents")
+	}
+}
+
+func TestRange(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	for _, k := range rand.Perm(100) {
+		rbt.Put(2*k, strconv.Itoa(2*k))
+	}
+	var keys []int
+	for r := range rbt.Range(11, 19) {
+		keys = append(keys, r.Key)
+	}
+	if !slices.Equal(keys, []int{12, 14, 16, 18}) {
+		t.Errorf("Range(11, 19) = %v", keys)
+	}
+	n := 0
+	for range rbt.Iterator() {
+		if n++; n == 5 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Errorf("break after %v keys", n)
+	}
+}
+
+// CLRS insert does at most two rotations, the LLRB ports rotate more often
+func TestRotations(t *testing.T) {
+	const n = 1 << 14
+	c := NewRBT[int, int]()
+	g := gm.NewRBT[int, int]()
+	for _, k := range rand.Perm(n) {
+		c.Put(k, k)
+		g.Put(k, k)
+	}
+	if c.Rotations() > 2*n {
+		t.Errorf("Rotations() = %v for %v inserts", c.Rotations(), n)
+	}
+	t.Logf("random inserts: clrs %v rotations, gemini %v", c.Rotations(), g.Rotations())
+	if h := c.Height(); h > 2*15 {
+		t.Errorf("Height() = %v for %v keys", h, n)
+	}
+}
+
+// ************ benchmarks against the LLRB trees ************
+
+var impls = append([]bench.Impl[int]{
+	{Name: "clrs", New: func() rbt.RBT[int, int] { return NewRBT[int, int]() }},
+}, bench.LLRB[int]()...)
+
+func BenchmarkGet(b *testing.B) {
+	bench.Random(b, impls, bench.Get[int])
+}
+
+// rotations/put is for filling the tree, reported by the trees that count them
+func BenchmarkPut(b *testing.B) {
+	bench.Random(b, impls, func(b *testing.B, t rbt.RBT[int, int], keys []int) {
+		if r, ok := t.(interface{ Rotations() int }); ok {
+			b.ReportMetric(float64(r.Rotations())/float64(len(keys)), "rotations/put")
+		}
+		bench.Put(b, t, keys)
+	})
+}
+
+// ns/op is per key visited
+func BenchmarkIterate(b *testing.B) {
+	bench.Random(b, impls, bench.Iterate[int])
+}
@@ -0,0 +1,132 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Codec converts values of one type to and from bytes. Encoded values
+// must be self-delimiting so they can be read back from a stream.
+type Codec[T any] interface {
+	// Name identifies the encoding, it is recorded in stream headers
+	Name() string
+	// Append appends the encoding of v to buf
+	Append(buf []byte, v T) ([]byte, error)
+	// Decode decodes a value from the front of buf and returns the number of bytes used
+	Decode(buf []byte) (T, int, error)
+}
+
+var ErrShort = errors.New("codec: short buffer")
+
+// Default returns Ordered for integer, float and string kinds and Gob otherwise
+func Default[T any]() Codec[T] {
+	var zero T
+	switch reflect.TypeOf(&zero).Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return Ordered[T]{}
+	}
+	return Gob[T]{}
+}
+
+// ************ ordered kinds ************
+
+// Ordered encodes integers as varints, floats as their IEEE 754 bits and
+// strings as a length followed by the bytes. T must have one of those
+// underlying kinds, named types included.
+type Ordered[T any] struct{}
+
+func (Ordered[T]) Name() string {
+	var zero T
+	return "ordered/" + reflect.TypeOf(&zero).Elem().Kind().String()
+}
+
+func (Ordered[T]) Append(buf []byte, v T) ([]byte, error) {
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(buf, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(buf, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(rv.Float())), nil
+	case reflect.String:
+		buf = binary.AppendUvarint(buf, uint64(rv.Len()))
+		return append(buf, rv.String()...), nil
+	}
+	return buf, fmt.Errorf("codec: %v is not an ordered kind", rv.Type())
+}
+
+func (Ordered[T]) Decode(buf []byte) (T, int, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, n := binary.Varint(buf)
+		if n <= 0 {
+			return v, 0, ErrShort
+		}
+		rv.SetInt(x)
+		return v, n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return v, 0, ErrShort
+		}
+		rv.SetUint(x)
+		return v, n, nil
+	case reflect.Float32, reflect.Float64:
+		if len(buf) < 8 {
+			return v, 0, ErrShort
+		}
+		rv.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(buf)))
+		return v, 8, nil
+	case reflect.String:
+		l, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < l {
+			return v, 0, ErrShort
+		}
+		rv.SetString(string(buf[n : n+int(l)]))
+		return v, n + int(l), nil
+	}
+	return v, 0, fmt.Errorf("codec: %v is not an ordered kind", rv.Type())
+}
+
+// ************ gob ************
+
+// Gob encodes each value as a length-prefixed, self-contained gob message.
+// It handles any type gob does, at the cost of repeating type information
+// for every value; supply a dedicated Codec for large trees of structs.
+type Gob[T any] struct{}
+
+func (Gob[T]) Name() string {
+	var zero T
+	return "gob/" + reflect.TypeOf(&zero).Elem().String()
+}
+
+func (Gob[T]) Append(buf []byte, v T) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(&v); err != nil {
+		return buf, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(b.Len()))
+	return append(buf, b.Bytes()...), nil
+}
+
+func (Gob[T]) Decode(buf []byte) (T, int, error) {
+	var v T
+	l, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(len(buf)-n) < l {
+		return v, 0, ErrShort
+	}
+	if err := gob.NewDecoder(bytes.NewReader(buf[n : n+int(l)])).Decode(&v); err != nil {
+		return v, 0, err
+	}
+	return v, n + int(l), nil
+}
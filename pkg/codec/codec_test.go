@@ -0,0 +1,273 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"slices"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+type celsius float32
+type name string
+
+func roundTrip[T comparable](t *testing.T, c Codec[T], vals ...T) {
+	t.Helper()
+	var buf []byte
+	var err error
+	for _, v := range vals {
+		if buf, err = c.Append(buf, v); err != nil {
+			t.Fatalf("%v: Append(%v): %v", c.Name(), v, err)
+		}
+	}
+	for _, want := range vals {
+		v, n, err := c.Decode(buf)
+		if err != nil || v != want {
+			t.Fatalf("%v: Decode() = %v, %v; want %v", c.Name(), v, err, want)
+		}
+		buf = buf[n:]
+	}
+	if len(buf) != 0 {
+		t.Errorf("%v: %v bytes left over", c.Name(), len(buf))
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	roundTrip(t, Default[int](), 0, -1, 1, math.MinInt, math.MaxInt)
+	roundTrip(t, Default[uint8](), 0, 1, 255)
+	roundTrip(t, Default[float64](), 0, -0.5, math.Inf(1), math.MaxFloat64)
+	roundTrip(t, Default[celsius](), -40, 100.25)
+	roundTrip(t, Default[string](), "", "a", "hello, 世界")
+	roundTrip(t, Default[name](), "x", "")
+
+	if _, ok := Default[name]().(Ordered[name]); !ok {
+		t.Errorf("Default[name]() is not Ordered")
+	}
+	if _, _, err := Default[string]().Decode([]byte{5, 'a'}); !errors.Is(err, ErrShort) {
+		t.Errorf("Decode(short) err = %v; want %v", err, ErrShort)
+	}
+}
+
+func TestGob(t *testing.T) {
+	type point struct{ X, Y int }
+	c := Default[point]()
+	if _, ok := c.(Gob[point]); !ok {
+		t.Fatalf("Default[point]() is not Gob")
+	}
+	roundTrip(t, c, point{}, point{1, 2}, point{-3, 4})
+}
+
+func TestMarshal(t *testing.T) {
+	pairs := []rbt.KeyValuePair[string, int]{{Key: "a", Val: 1}, {Key: "b", Val: 2}, {Key: "c", Val: 3}}
+	it := func(yield func(rbt.KeyValuePair[string, int]) bool) {
+		for _, p := range pairs {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+	kc, vc := Default[string](), Default[int]()
+
+	data, err := Marshal(len(pairs), it, kc, vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unmarshal(data, kc, vc)
+	if err != nil || !slices.Equal(got, pairs) {
+		t.Fatalf("Unmarshal() = %v, %v; want %v", got, err, pairs)
+	}
+
+	if _, err := Marshal(len(pairs)+1, it, kc, vc); err == nil {
+		t.Errorf("Marshal() with wrong count: err = nil")
+	}
+
+	bad := slices.Clone(data)
+	bad[len(magic)] = Version + 1
+	if _, err := Unmarshal(bad, kc, vc); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Unmarshal(modified) err = %v; want %v", err, ErrChecksum)
+	}
+
+	// out of order keys are rejected even with a valid checksum
+	pairs[0], pairs[2] = pairs[2], pairs[0]
+	data, _ = Marshal(len(pairs), it, kc, vc)
+	if _, err := Unmarshal(data, kc, vc); !errors.Is(err, ErrOrder) {
+		t.Errorf("Unmarshal(unsorted) err = %v; want %v", err, ErrOrder)
+	}
+}
+
+// a stream whose header claims far more pairs than it holds is an error,
+// the count must not size an allocation
+func TestReadFromForgedCount(t *testing.T) {
+	kc, vc := Default[string](), Default[int]()
+	data := append([]byte(magic), Version)
+	data = AppendString(data, kc.Name())
+	data = AppendString(data, vc.Name())
+	data = binary.AppendUvarint(data, 1<<47)
+	data, _ = kc.Append(data, "a")
+	data, _ = vc.Append(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, crc32.Checksum(data, castagnoli))
+
+	if _, _, err := ReadFrom(bytes.NewReader(data), NewBinaryDecoder(kc, vc)); err == nil {
+		t.Errorf("ReadFrom() with a forged count: err = nil")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	strs := []rbt.KeyValuePair[string, int]{{Key: "a", Val: 1}, {Key: "b\"", Val: 2}, {Key: "c", Val: 3}}
+	data, err := MarshalJSON(slices.Values(strs))
+	if err != nil || string(data) != `{"a":1,"b\"":2,"c":3}` {
+		t.Fatalf("MarshalJSON() = %s, %v", data, err)
+	}
+	if got, err := UnmarshalJSON[string, int](data); err != nil || !slices.Equal(got, strs) {
+		t.Errorf("UnmarshalJSON(%s) = %v, %v; want %v", data, got, err, strs)
+	}
+
+	nums := []rbt.KeyValuePair[float64, string]{{Key: -1.5, Val: "x"}, {Key: 2, Val: "y"}}
+	data, err = MarshalJSON(slices.Values(nums))
+	if err != nil || string(data) != `[[-1.5,"x"],[2,"y"]]` {
+		t.Fatalf("MarshalJSON() = %s, %v", data, err)
+	}
+	if got, err := UnmarshalJSON[float64, string](data); err != nil || !slices.Equal(got, nums) {
+		t.Errorf("UnmarshalJSON(%s) = %v, %v; want %v", data, got, err, nums)
+	}
+
+	// unsorted input is sorted, the last of repeated keys wins
+	got, err := UnmarshalJSON[name, int]([]byte(`{"c":3, "a":1, "c":4, "b":2}`))
+	want := []rbt.KeyValuePair[name, int]{{Key: "a", Val: 1}, {Key: "b", Val: 2}, {Key: "c", Val: 4}}
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("UnmarshalJSON(unsorted) = %v, %v; want %v", got, err, want)
+	}
+
+	for _, bad := range []string{`{}`, `{"a":"x"}`, `[[1]]`, `[[1,"a",2]]`, `[1]`, `[[1,"a"]] x`} {
+		if _, err := UnmarshalJSON[int, int]([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) err = nil", bad)
+		}
+	}
+}
+
+type testNode struct {
+	key         int
+	size        int
+	red         bool
+	left, right *testNode
+}
+
+// check the left leaning red-black invariants, keys and sizes, return the black height
+func checkLLRB(t *testing.T, x *testNode, lo int, hi int) int {
+	if x == nil {
+		return 0
+	}
+	if x.key < lo || x.key > hi {
+		t.Fatalf("key %v outside [%v, %v]", x.key, lo, hi)
+	}
+	if x.right != nil && x.right.red {
+		t.Fatalf("right leaning red link at %v", x.key)
+	}
+	if x.red && x.left != nil && x.left.red {
+		t.Fatalf("two red links in a row at %v", x.key)
+	}
+	n := 1
+	for _, c := range []*testNode{x.left, x.right} {
+		if c != nil {
+			n += c.size
+		}
+	}
+	if x.size != n {
+		t.Fatalf("size = %v at %v; want %v", x.size, x.key, n)
+	}
+	lh, rh := checkLLRB(t, x.left, lo, x.key-1), checkLLRB(t, x.right, x.key+1, hi)
+	if lh != rh {
+		t.Fatalf("black heights %v and %v differ at %v", lh, rh, x.key)
+	}
+	if !x.red {
+		lh++
+	}
+	return lh
+}
+
+func TestBuildLLRB(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		pairs := make([]rbt.KeyValuePair[int, int], n)
+		for i := range pairs {
+			pairs[i] = rbt.KeyValuePair[int, int]{Key: i, Val: i}
+		}
+		root := BuildLLRB(pairs, func(p rbt.KeyValuePair[int, int], left, right *testNode, size int, red bool) *testNode {
+			return &testNode{key: p.Key, size: size, red: red, left: left, right: right}
+		})
+		if n == 0 {
+			if root != nil {
+				t.Fatalf("BuildLLRB of nothing = %v", root)
+			}
+			continue
+		}
+		if root.red {
+			t.Fatalf("n = %v: red root", n)
+		}
+		checkLLRB(t, root, 0, n-1)
+	}
+}
+
+func TestBuildBalanced(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		pairs := make([]rbt.KeyValuePair[int, int], n)
+		for i := range pairs {
+			pairs[i] = rbt.KeyValuePair[int, int]{Key: i, Val: i}
+		}
+		var keys []int
+		var height func(x *testNode) int
+		height = func(x *testNode) int {
+			if x == nil {
+				return 0
+			}
+			l := height(x.left)
+			keys = append(keys, x.key)
+			r := height(x.right)
+			if l-r > 1 || r-l > 1 {
+				t.Fatalf("n = %v: heights %v and %v at %v", n, l, r, x.key)
+			}
+			return 1 + max(l, r)
+		}
+		height(BuildBalanced(pairs, func(p rbt.KeyValuePair[int, int], left, right *testNode) *testNode {
+			return &testNode{key: p.Key, left: left, right: right}
+		}))
+		if len(keys) != n || !slices.IsSorted(keys) {
+			t.Fatalf("n = %v: in order keys %v", n, keys)
+		}
+	}
+}
+
+// test that a zero Codecs falls back to the defaults and SetCodecs is used
+func TestCodecs(t *testing.T) {
+	pairs := []rbt.KeyValuePair[int, string]{{Key: 1, Val: "one"}, {Key: 2, Val: "two"}}
+	seq := func(yield func(rbt.KeyValuePair[int, string]) bool) {
+		for _, p := range pairs {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+	for _, c := range []*Codecs[int, string]{{}, func() *Codecs[int, string] {
+		c := &Codecs[int, string]{}
+		c.SetCodecs(Gob[int]{}, Gob[string]{})
+		return c
+	}()} {
+		data, err := MarshalBinary(c, len(pairs), seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []rbt.KeyValuePair[int, string]
+		if err := UnmarshalBinary(c, data, func(p []rbt.KeyValuePair[int, string]) { got = p }); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, pairs) {
+			t.Errorf("round trip = %v; want %v", got, pairs)
+		}
+	}
+	if err := LoadJSON([]byte("null"), func([]rbt.KeyValuePair[int, string]) { t.Errorf("null loaded pairs") }); err != nil {
+		t.Errorf("LoadJSON(null) = %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// A serialized tree is laid out as
+//
+//	magic    "GTRB"
+//	version  1 byte
+//	codecs   uvarint length + key codec name, uvarint length + value codec name
+//	count    uvarint number of entries
+//	entries  key, value pairs in ascending key order
+//	checksum CRC-32C of everything before it, 4 bytes little endian
+
+const Version = 1
+
+var magic = []byte("GTRB")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	ErrFormat   = errors.New("codec: not a serialized tree")
+	ErrVersion  = errors.New("codec: unsupported format version")
+	ErrChecksum = errors.New("codec: checksum mismatch")
+	ErrOrder    = errors.New("codec: keys are not in ascending order")
+)
+
+// Marshal serializes the n pairs produced by it, which must be in ascending key order
+func Marshal[K constraints.Ordered, V any](n int, it func(func(rbt.KeyValuePair[K, V]) bool), kc Codec[K], vc Codec[V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := WriteTo(&buf, NewBinaryEncoder(kc, vc), n, it); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal checks and decodes a serialized tree into pairs in ascending key order
+func Unmarshal[K constraints.Ordered, V any](data []byte, kc Codec[K], vc Codec[V]) ([]rbt.KeyValuePair[K, V], error) {
+	if len(data) < len(magic)+1+4 || string(data[:len(magic)]) != string(magic) {
+		return nil, ErrFormat
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, castagnoli) != sum {
+		return nil, ErrChecksum
+	}
+	if body[len(magic)] != Version {
+		return nil, ErrVersion
+	}
+	buf := body[len(magic)+1:]
+
+	for _, want := range []string{kc.Name(), vc.Name()} {
+		name, n, err := DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		if name != want {
+			return nil, fmt.Errorf("codec: stream was written with codec %q, reading with %q", name, want)
+		}
+		buf = buf[n:]
+	}
+
+	count, n := binary.Uvarint(buf)
+	if n <= 0 || count > uint64(len(buf)) {
+		return nil, ErrFormat
+	}
+	buf = buf[n:]
+
+	pairs := make([]rbt.KeyValuePair[K, V], 0, count)
+	for i := uint64(0); i < count; i++ {
+		k, n, err := kc.Decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[n:]
+		v, n, err := vc.Decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[n:]
+		if i > 0 && !(pairs[i-1].Key < k) {
+			return nil, ErrOrder
+		}
+		pairs = append(pairs, rbt.KeyValuePair[K, V]{Key: k, Val: v})
+	}
+	if len(buf) != 0 {
+		return nil, ErrFormat
+	}
+	return pairs, nil
+}
+
+// AppendString appends s as its uvarint length followed by its bytes, the
+// way codec names are recorded in headers
+func AppendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// DecodeString decodes a string written by AppendString and returns the
+// number of bytes it used, or ErrShort if buf does not hold all of it
+func DecodeString(buf []byte) (string, int, error) {
+	l, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(len(buf)-n) < l {
+		return "", 0, ErrShort
+	}
+	return string(buf[n : n+int(l)]), n + int(l), nil
+}
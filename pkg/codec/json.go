@@ -0,0 +1,149 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalJSON encodes the pairs produced by it, in the order produced. trees
+// with string keys become a JSON object, {"a":1,"b":2}, any other key type
+// becomes an array of key, value pairs, [[1,"a"],[2,"b"]].
+func MarshalJSON[K constraints.Ordered, V any](it func(func(rbt.KeyValuePair[K, V]) bool)) ([]byte, error) {
+	object := isString[K]()
+	var buf bytes.Buffer
+	open, close := byte('['), byte(']')
+	if object {
+		open, close = '{', '}'
+	}
+	buf.WriteByte(open)
+
+	first := true
+	for r := range it {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		k, err := json.Marshal(r.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.Val)
+		if err != nil {
+			return nil, err
+		}
+		if object {
+			buf.Write(k)
+			buf.WriteByte(':')
+			buf.Write(v)
+		} else {
+			buf.WriteByte('[')
+			buf.Write(k)
+			buf.WriteByte(',')
+			buf.Write(v)
+			buf.WriteByte(']')
+		}
+	}
+	buf.WriteByte(close)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes the output of MarshalJSON into pairs in ascending key
+// order. input that is not sorted is accepted, when a key repeats the last
+// value wins as it does for encoding/json.
+func UnmarshalJSON[K constraints.Ordered, V any](data []byte) ([]rbt.KeyValuePair[K, V], error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	object := isString[K]()
+	open, close := json.Delim('['), json.Delim(']')
+	if object {
+		open, close = '{', '}'
+	}
+	if err := expect(dec, open); err != nil {
+		return nil, err
+	}
+
+	var pairs []rbt.KeyValuePair[K, V]
+	sorted := true
+	for dec.More() {
+		var r rbt.KeyValuePair[K, V]
+		if object {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			reflect.ValueOf(&r.Key).Elem().SetString(tok.(string))
+			if err := dec.Decode(&r.Val); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := expect(dec, '['); err != nil {
+				return nil, err
+			}
+			if err := dec.Decode(&r.Key); err != nil {
+				return nil, err
+			}
+			if err := dec.Decode(&r.Val); err != nil {
+				return nil, err
+			}
+			if err := expect(dec, ']'); err != nil {
+				return nil, err
+			}
+		}
+		if n := len(pairs); n > 0 && !(pairs[n-1].Key < r.Key) {
+			sorted = false
+		}
+		pairs = append(pairs, r)
+	}
+	if err := expect(dec, close); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("codec: unexpected data after JSON %v", close)
+	}
+
+	if !sorted {
+		slices.SortStableFunc(pairs, func(a, b rbt.KeyValuePair[K, V]) int {
+			switch {
+			case a.Key < b.Key:
+				return -1
+			case a.Key > b.Key:
+				return 1
+			}
+			return 0
+		})
+		// keep the last of each run of equal keys
+		out := pairs[:0]
+		for i, r := range pairs {
+			if i+1 < len(pairs) && pairs[i+1].Key == r.Key {
+				continue
+			}
+			out = append(out, r)
+		}
+		pairs = out
+	}
+	return pairs, nil
+}
+
+// read the next token and check that it is the delimiter d
+func expect(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("codec: expected JSON %v, found %v", d, tok)
+	}
+	return nil
+}
+
+func isString[K any]() bool {
+	var zero K
+	return reflect.TypeOf(&zero).Elem().Kind() == reflect.String
+}
@@ -0,0 +1,369 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Encoder writes a stream of key value pairs. WriteTo calls Begin with the
+// number of pairs, Encode once for each pair in ascending key order, then
+// End. an Encoder may keep state between the calls, so it must not be used
+// for two streams at once
+type Encoder[K constraints.Ordered, V any] interface {
+	Begin(w io.Writer, n int) error
+	Encode(w io.Writer, key K, val V) error
+	End(w io.Writer) error
+}
+
+// Decoder reads a stream written by the matching Encoder. Begin returns the
+// number of pairs, or -1 if the stream does not record it. Decode returns
+// io.EOF after the last pair
+type Decoder[K constraints.Ordered, V any] interface {
+	Begin(r *bufio.Reader) (int, error)
+	Decode(r *bufio.Reader) (K, V, error)
+}
+
+// size of the buffers WriteTo and ReadFrom put around their streams
+const bufferSize = 64 << 10
+
+// WriteTo streams the n pairs produced by it to w through a buffer and
+// returns the number of bytes written
+func WriteTo[K constraints.Ordered, V any](w io.Writer, enc Encoder[K, V], n int, it func(func(rbt.KeyValuePair[K, V]) bool)) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriterSize(cw, bufferSize)
+	if err := enc.Begin(bw, n); err != nil {
+		return cw.n, err
+	}
+	count := 0
+	for r := range it {
+		if err := enc.Encode(bw, r.Key, r.Val); err != nil {
+			return cw.n, err
+		}
+		count++
+	}
+	if count != n {
+		return cw.n, fmt.Errorf("codec: iterator produced %v pairs, expected %v", count, n)
+	}
+	if err := enc.End(bw); err != nil {
+		return cw.n, err
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ReadFrom reads a stream of pairs in strictly ascending key order from r,
+// ready for a linear time bulk load. it returns the number of bytes read from r
+func ReadFrom[K constraints.Ordered, V any](r io.Reader, dec Decoder[K, V]) ([]rbt.KeyValuePair[K, V], int64, error) {
+	cr := &countReader{r: r}
+	br := bufio.NewReaderSize(cr, bufferSize)
+	n, err := dec.Begin(br)
+	if err != nil {
+		return nil, cr.n, err
+	}
+	// the count comes from the stream, so only trust it for a bounded
+	// preallocation and let append grow the rest
+	pairs := make([]rbt.KeyValuePair[K, V], 0, min(max(n, 0), 1<<16))
+	for {
+		k, v, err := dec.Decode(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, cr.n, err
+		}
+		if len(pairs) > 0 && !(pairs[len(pairs)-1].Key < k) {
+			return nil, cr.n, ErrOrder
+		}
+		pairs = append(pairs, rbt.KeyValuePair[K, V]{Key: k, Val: v})
+	}
+	if n >= 0 && len(pairs) != n {
+		return nil, cr.n, ErrFormat
+	}
+	return pairs, cr.n, nil
+}
+
+// ************ binary ************
+
+// BinaryEncoder writes the format of Marshal without holding the stream in memory
+type BinaryEncoder[K constraints.Ordered, V any] struct {
+	kc  Codec[K]
+	vc  Codec[V]
+	crc hash.Hash32
+	buf []byte
+}
+
+// create a binary encoder, nil codecs select codec.Default
+func NewBinaryEncoder[K constraints.Ordered, V any](kc Codec[K], vc Codec[V]) *BinaryEncoder[K, V] {
+	kc, vc = defaults(kc, vc)
+	return &BinaryEncoder[K, V]{kc: kc, vc: vc}
+}
+
+func (e *BinaryEncoder[K, V]) Begin(w io.Writer, n int) error {
+	e.crc = crc32.New(castagnoli)
+	buf := append(e.buf[:0], magic...)
+	buf = append(buf, Version)
+	buf = AppendString(buf, e.kc.Name())
+	buf = AppendString(buf, e.vc.Name())
+	buf = binary.AppendUvarint(buf, uint64(n))
+	return e.write(w, buf)
+}
+
+func (e *BinaryEncoder[K, V]) Encode(w io.Writer, key K, val V) error {
+	buf, err := e.kc.Append(e.buf[:0], key)
+	if err != nil {
+		return err
+	}
+	if buf, err = e.vc.Append(buf, val); err != nil {
+		return err
+	}
+	return e.write(w, buf)
+}
+
+func (e *BinaryEncoder[K, V]) End(w io.Writer) error {
+	_, err := w.Write(binary.LittleEndian.AppendUint32(e.buf[:0], e.crc.Sum32()))
+	return err
+}
+
+func (e *BinaryEncoder[K, V]) write(w io.Writer, buf []byte) error {
+	e.buf = buf
+	e.crc.Write(buf)
+	_, err := w.Write(buf)
+	return err
+}
+
+// BinaryDecoder reads the format of Marshal a value at a time, verifying
+// the checksum when it reaches the end of the stream. it reads ahead of the
+// value it is decoding, so the stream must be the last thing in r
+type BinaryDecoder[K constraints.Ordered, V any] struct {
+	kc     Codec[K]
+	vc     Codec[V]
+	crc    hash.Hash32
+	win    []byte // bytes read from r and not yet decoded start at pos
+	pos    int
+	remain uint64 // pairs left to decode
+}
+
+// create a binary decoder, nil codecs select codec.Default
+func NewBinaryDecoder[K constraints.Ordered, V any](kc Codec[K], vc Codec[V]) *BinaryDecoder[K, V] {
+	kc, vc = defaults(kc, vc)
+	return &BinaryDecoder[K, V]{kc: kc, vc: vc}
+}
+
+func (d *BinaryDecoder[K, V]) Begin(r *bufio.Reader) (int, error) {
+	d.crc = crc32.New(castagnoli)
+	d.win, d.pos = d.win[:0], 0
+	err := d.next(r, func(b []byte) (int, error) {
+		if len(b) < len(magic)+1 {
+			return 0, ErrShort
+		}
+		if string(b[:len(magic)]) != string(magic) {
+			return 0, ErrFormat
+		}
+		if b[len(magic)] != Version {
+			return 0, ErrVersion
+		}
+		return len(magic) + 1, nil
+	})
+	if err == io.ErrUnexpectedEOF {
+		return 0, ErrFormat
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, want := range []string{d.kc.Name(), d.vc.Name()} {
+		var name string
+		if err := d.next(r, func(b []byte) (n int, err error) {
+			name, n, err = DecodeString(b)
+			return n, err
+		}); err != nil {
+			return 0, err
+		}
+		if name != want {
+			return 0, fmt.Errorf("codec: stream was written with codec %q, reading with %q", name, want)
+		}
+	}
+	if err := d.next(r, func(b []byte) (n int, err error) {
+		if d.remain, n = binary.Uvarint(b); n <= 0 {
+			return 0, ErrShort
+		}
+		return n, nil
+	}); err != nil {
+		return 0, err
+	}
+	if d.remain > 1<<48 {
+		return 0, ErrFormat
+	}
+	return int(d.remain), nil
+}
+
+func (d *BinaryDecoder[K, V]) Decode(r *bufio.Reader) (K, V, error) {
+	var k K
+	var v V
+	if d.remain == 0 {
+		sum := d.crc.Sum32()
+		err := d.next(r, func(b []byte) (int, error) {
+			if len(b) < 4 {
+				return 0, ErrShort
+			}
+			if binary.LittleEndian.Uint32(b) != sum {
+				return 0, ErrChecksum
+			}
+			return 4, nil
+		})
+		if err != nil {
+			return k, v, err
+		}
+		return k, v, io.EOF
+	}
+	err := d.next(r, func(b []byte) (n int, err error) {
+		k, n, err = d.kc.Decode(b)
+		return n, err
+	})
+	if err == nil {
+		err = d.next(r, func(b []byte) (n int, err error) {
+			v, n, err = d.vc.Decode(b)
+			return n, err
+		})
+	}
+	d.remain--
+	return k, v, err
+}
+
+// decode the next value with fn, reading more of r into the window each
+// time fn reports a short buffer
+func (d *BinaryDecoder[K, V]) next(r io.Reader, fn func([]byte) (int, error)) error {
+	for {
+		n, err := fn(d.win[d.pos:])
+		if err == nil {
+			d.crc.Write(d.win[d.pos : d.pos+n])
+			d.pos += n
+			return nil
+		}
+		if !errors.Is(err, ErrShort) {
+			return err
+		}
+		if err := d.fill(r); err != nil {
+			return err
+		}
+	}
+}
+
+// read more of r into the window, growing it if it is full
+func (d *BinaryDecoder[K, V]) fill(r io.Reader) error {
+	if d.pos > 0 {
+		d.win = d.win[:copy(d.win, d.win[d.pos:])]
+		d.pos = 0
+	}
+	if len(d.win) == cap(d.win) {
+		d.win = slices.Grow(d.win, max(cap(d.win), 4096))
+	}
+	for {
+		m, err := r.Read(d.win[len(d.win):cap(d.win)])
+		d.win = d.win[:len(d.win)+m]
+		if m > 0 {
+			return nil
+		}
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ************ JSON lines ************
+
+// JSONLinesEncoder writes one [key, value] JSON array per line. it keeps
+// no state, so one value can encode any number of streams
+type JSONLinesEncoder[K constraints.Ordered, V any] struct{}
+
+func (JSONLinesEncoder[K, V]) Begin(w io.Writer, n int) error { return nil }
+
+func (JSONLinesEncoder[K, V]) Encode(w io.Writer, key K, val V) error {
+	line, err := json.Marshal([2]any{key, val})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+func (JSONLinesEncoder[K, V]) End(w io.Writer) error { return nil }
+
+// JSONLinesDecoder reads the output of JSONLinesEncoder, skipping blank lines
+type JSONLinesDecoder[K constraints.Ordered, V any] struct{}
+
+// a JSON lines stream does not record its length
+func (JSONLinesDecoder[K, V]) Begin(r *bufio.Reader) (int, error) { return -1, nil }
+
+func (JSONLinesDecoder[K, V]) Decode(r *bufio.Reader) (K, V, error) {
+	var k K
+	var v V
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return k, v, err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return k, v, err
+		}
+		var pair [2]json.RawMessage
+		if err := json.Unmarshal(line, &pair); err != nil {
+			return k, v, err
+		}
+		if err := json.Unmarshal(pair[0], &k); err != nil {
+			return k, v, err
+		}
+		err = json.Unmarshal(pair[1], &v)
+		return k, v, err
+	}
+}
+
+func defaults[K any, V any](kc Codec[K], vc Codec[V]) (Codec[K], Codec[V]) {
+	if kc == nil {
+		kc = Default[K]()
+	}
+	if vc == nil {
+		vc = Default[V]()
+	}
+	return kc, vc
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,131 @@
+package codec
+
+import (
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// the pieces every tree type needs to implement the encoding interfaces.
+// a tree embeds Codecs and supplies a load function that replaces its
+// contents with pairs sorted by key, usually built by BuildLLRB or
+// BuildBalanced, and its methods reduce to calls to the functions here.
+
+// Codecs holds the key and value codecs a tree uses for MarshalBinary and
+// UnmarshalBinary. embedding it gives the tree SetCodecs; the zero value
+// encodes with Default for both.
+type Codecs[K constraints.Ordered, V any] struct {
+	key Codec[K]
+	val Codec[V]
+}
+
+// SetCodecs selects the key and value encodings used by MarshalBinary and
+// UnmarshalBinary. a nil codec falls back to Default.
+func (c *Codecs[K, V]) SetCodecs(kc Codec[K], vc Codec[V]) {
+	c.key = kc
+	c.val = vc
+}
+
+// MarshalBinary encodes the n pairs of it with the codecs selected in c
+func MarshalBinary[K constraints.Ordered, V any](c *Codecs[K, V], n int, it func(func(rbt.KeyValuePair[K, V]) bool)) ([]byte, error) {
+	kc, vc := defaults(c.key, c.val)
+	return Marshal(n, it, kc, vc)
+}
+
+// UnmarshalBinary decodes data with the codecs selected in c and hands the
+// sorted pairs to load
+func UnmarshalBinary[K constraints.Ordered, V any](c *Codecs[K, V], data []byte, load func([]rbt.KeyValuePair[K, V])) error {
+	kc, vc := defaults(c.key, c.val)
+	pairs, err := Unmarshal(data, kc, vc)
+	if err != nil {
+		return err
+	}
+	load(pairs)
+	return nil
+}
+
+// LoadJSON decodes data as written by MarshalJSON and hands the sorted
+// pairs to load. null leaves the tree as it is, the way encoding/json
+// treats null for other types
+func LoadJSON[K constraints.Ordered, V any](data []byte, load func([]rbt.KeyValuePair[K, V])) error {
+	if string(data) == "null" {
+		return nil
+	}
+	pairs, err := UnmarshalJSON[K, V](data)
+	if err != nil {
+		return err
+	}
+	load(pairs)
+	return nil
+}
+
+// BuildLLRB lays out pairs sorted by key as a left leaning red-black tree in
+// linear time. node makes the node for one pair once its subtrees are built,
+// given the size of its subtree and whether its link is red; the zero N
+// stands for an empty subtree. the tree is laid out as a 2-3 tree of black
+// height h with every leaf at the same depth, which always exists when
+// 2^h-1 <= n <= 3^h-1.
+func BuildLLRB[K constraints.Ordered, V any, N any](pairs []rbt.KeyValuePair[K, V], node func(p rbt.KeyValuePair[K, V], left N, right N, size int, red bool) N) N {
+	h := 0
+	for 1<<(h+1)-1 <= len(pairs) {
+		h++
+	}
+	return buildHeight(pairs, h, node)
+}
+
+// build a subtree of black height h. it is a 2-node when the keys fit under
+// two subtrees of height h-1, otherwise a 3-node, a black node with a red left child.
+func buildHeight[K constraints.Ordered, V any, N any](pairs []rbt.KeyValuePair[K, V], h int, node func(rbt.KeyValuePair[K, V], N, N, int, bool) N) N {
+	n := len(pairs)
+	if n == 0 {
+		var empty N
+		return empty
+	}
+	if n/2 <= capacity(h-1) {
+		mid := n / 2
+		l := buildHeight(pairs[:mid], h-1, node)
+		r := buildHeight(pairs[mid+1:], h-1, node)
+		return node(pairs[mid], l, r, n, false)
+	}
+
+	// split the remaining n-2 keys as evenly as possible over three subtrees
+	q, r := (n-2)/3, (n-2)%3
+	a, b := q, q
+	if r > 0 {
+		a++
+	}
+	if r > 1 {
+		b++
+	}
+	ll := buildHeight(pairs[:a], h-1, node)
+	lr := buildHeight(pairs[a+1:a+1+b], h-1, node)
+	l := node(pairs[a], ll, lr, a+b+1, true)
+	xr := buildHeight(pairs[a+2+b:], h-1, node)
+	return node(pairs[a+1+b], l, xr, n, false)
+}
+
+// the most keys a 2-3 tree of black height h can hold, 3^h-1
+func capacity(h int) int {
+	c := 1
+	for ; h > 0; h-- {
+		if c > (1<<62)/3 {
+			return 1 << 62
+		}
+		c *= 3
+	}
+	return c - 1
+}
+
+// BuildBalanced lays out pairs sorted by key as a binary search tree in
+// linear time, splitting at the middle so the subtrees of every node differ
+// in size by at most one. node makes the node for one pair once its
+// subtrees are built; the zero N stands for an empty subtree.
+func BuildBalanced[K constraints.Ordered, V any, N any](pairs []rbt.KeyValuePair[K, V], node func(p rbt.KeyValuePair[K, V], left N, right N) N) N {
+	if len(pairs) == 0 {
+		var empty N
+		return empty
+	}
+	mid := len(pairs) / 2
+	l := BuildBalanced(pairs[:mid], node)
+	r := BuildBalanced(pairs[mid+1:], node)
+	return node(pairs[mid], l, r)
+}
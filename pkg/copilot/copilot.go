@@ -3,6 +3,7 @@ package copilot
 import (
 	"golang.org/x/exp/constraints"
 
+	"sqirvy.xyz/go-tree-iterator/codec"
 	rbt "sqirvy.xyz/go-tree-iterator/rbt"
 )
 
@@ -50,6 +51,8 @@ func (n *Node[K, V]) Size() int {
 
 // CopilotRbt is a red-black tree
 type CopilotRbt[K constraints.Ordered, V any] struct {
+	codec.Codecs[K, V] // key and value codecs for MarshalBinary
+
 	root *Node[K, V]
 }
 
@@ -85,10 +88,7 @@ func (t *CopilotRbt[K, V]) Get(key K) (V, bool) {
 
 // get the value of a key from a specified subtree
 func (t *CopilotRbt[K, V]) get(x *Node[K, V], key K) (V, bool) {
-	if x == nil {
-		return t.root.val, false
-	}
-
+	var zero V
 	for x != nil {
 		cmp := compare(key, x.key)
 		if cmp < 0 {
@@ -99,8 +99,7 @@ func (t *CopilotRbt[K, V]) get(x *Node[K, V], key K) (V, bool) {
 			return x.val, true
 		}
 	}
-	return t.root.val, false
-
+	return zero, false
 }
 
 // insert a key-value pair into the red-black tree
@@ -138,6 +137,95 @@ func (t *CopilotRbt[K, V]) put(h *Node[K, V], key K, val V) *Node[K, V] {
 	return h
 }
 
+// get the smallest key in the tree
+func (t *CopilotRbt[K, V]) Min() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.min(t.root).key, true
+}
+
+// get the node with the smallest key in the subtree rooted at x
+func (t *CopilotRbt[K, V]) min(x *Node[K, V]) *Node[K, V] {
+	for x.left != nil {
+		x = x.left
+	}
+	return x
+}
+
+// get the largest key in the tree
+func (t *CopilotRbt[K, V]) Max() (K, bool) {
+	if t.IsEmpty() {
+		var zero K
+		return zero, false
+	}
+	return t.max(t.root).key, true
+}
+
+// get the node with the largest key in the subtree rooted at x
+func (t *CopilotRbt[K, V]) max(x *Node[K, V]) *Node[K, V] {
+	for x.right != nil {
+		x = x.right
+	}
+	return x
+}
+
+// remove the smallest key and its value from the tree
+func (t *CopilotRbt[K, V]) DeleteMin() {
+	if t.IsEmpty() {
+		return
+	}
+	if !t.root.left.IsRed() && !t.root.right.IsRed() {
+		t.root.color = red
+	}
+	t.root = t.deleteMin(t.root)
+	if !t.IsEmpty() {
+		t.root.color = black
+	}
+}
+
+// remove the smallest key from the subtree rooted at h
+func (t *CopilotRbt[K, V]) deleteMin(h *Node[K, V]) *Node[K, V] {
+	if h.left == nil {
+		return nil
+	}
+	if !h.left.IsRed() && !h.left.left.IsRed() {
+		h = t.moveRedLeft(h)
+	}
+	h.left = t.deleteMin(h.left)
+	return t.balance(h)
+}
+
+// remove the largest key and its value from the tree
+func (t *CopilotRbt[K, V]) DeleteMax() {
+	if t.IsEmpty() {
+		return
+	}
+	if !t.root.left.IsRed() && !t.root.right.IsRed() {
+		t.root.color = red
+	}
+	t.root = t.deleteMax(t.root)
+	if !t.IsEmpty() {
+		t.root.color = black
+	}
+}
+
+// remove the largest key from the subtree rooted at h
+func (t *CopilotRbt[K, V]) deleteMax(h *Node[K, V]) *Node[K, V] {
+	if h.left.IsRed() {
+		h = t.rotateRight(h)
+	}
+	if h.right == nil {
+		return nil
+	}
+	if !h.right.IsRed() && !h.right.left.IsRed() {
+		h = t.moveRedRight(h)
+	}
+	h.right = t.deleteMax(h.right)
+	return t.balance(h)
+}
+
 // ************ RBT helper functions ************
 
 // Red-Black Rotations
@@ -169,6 +257,44 @@ func (t *CopilotRbt[K, V]) flipColors(h *Node[K, V]) {
 	h.right.color = !h.right.color
 }
 
+// assuming that h is red and both h.left and h.left.left
+// are black, make h.left or one of its children red
+func (t *CopilotRbt[K, V]) moveRedLeft(h *Node[K, V]) *Node[K, V] {
+	t.flipColors(h)
+	if h.right.left.IsRed() {
+		h.right = t.rotateRight(h.right)
+		h = t.rotateLeft(h)
+		t.flipColors(h)
+	}
+	return h
+}
+
+// assuming that h is red and both h.right and h.right.left
+// are black, make h.right or one of its children red
+func (t *CopilotRbt[K, V]) moveRedRight(h *Node[K, V]) *Node[K, V] {
+	t.flipColors(h)
+	if h.left.left.IsRed() {
+		h = t.rotateRight(h)
+		t.flipColors(h)
+	}
+	return h
+}
+
+// restore red-black tree invariant
+func (t *CopilotRbt[K, V]) balance(h *Node[K, V]) *Node[K, V] {
+	if h.right.IsRed() && !h.left.IsRed() {
+		h = t.rotateLeft(h)
+	}
+	if h.left.IsRed() && h.left.left.IsRed() {
+		h = t.rotateRight(h)
+	}
+	if h.left.IsRed() && h.right.IsRed() {
+		t.flipColors(h)
+	}
+	h.size = h.left.Size() + h.right.Size() + 1
+	return h
+}
+
 // ************ Ordered Symbol Table Functions ***********
 
 // return all keys in the range [lo..hi] in ascending order
@@ -213,16 +339,34 @@ func (bst *CopilotRbt[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
 
 func (t *CopilotRbt[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
 	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
-		var inorder func(*Node[K, V])
-		inorder = func(n *Node[K, V]) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
 			if n == nil {
-				return
+				return true
+			}
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) &&
+				inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (t *CopilotRbt[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			if lo < n.key && !inorder(n.left) {
+				return false
 			}
-			inorder(n.left)
-			if !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
-				return
+			if lo <= n.key && n.key <= hi && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
+				return false
 			}
-			inorder(n.right)
+			return hi <= n.key || inorder(n.right)
 		}
 		inorder(t.root)
 	}
@@ -0,0 +1,14 @@
+package copilot
+
+import "sqirvy.xyz/go-tree-iterator/codec"
+
+// MarshalJSON implements json.Marshaler. a tree with string keys encodes as an
+// object with its keys in order, other trees as an array of [key, value] pairs.
+func (t *CopilotRbt[K, V]) MarshalJSON() ([]byte, error) {
+	return codec.MarshalJSON(t.Iterator())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it replaces the contents of the tree
+func (t *CopilotRbt[K, V]) UnmarshalJSON(data []byte) error {
+	return codec.LoadJSON(data, t.load)
+}
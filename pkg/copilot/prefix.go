@@ -0,0 +1,37 @@
+package copilot
+
+import (
+	rbt "sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// PrefixScan returns an iterator over the entries whose keys start with prefix,
+// in key order, in O(log n + k). see rbt.PrefixScan
+func PrefixScan[K ~string, V any](t *CopilotRbt[K, V], prefix K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.PrefixScan(t.ascend, prefix)
+}
+
+// LongestPrefixOf returns the longest key in the tree that is a prefix of s.
+// see rbt.LongestPrefixOf
+func LongestPrefixOf[K ~string, V any](t *CopilotRbt[K, V], s K) (K, bool) {
+	return rbt.LongestPrefixOf(t.neighbors().Floor, s)
+}
+
+// ascend iterates in order over the keys >= from, skipping the subtrees below it
+func (t *CopilotRbt[K, V]) ascend(from K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(x *Node[K, V]) bool {
+			if x == nil {
+				return true
+			}
+			if from < x.key && !inorder(x.left) {
+				return false
+			}
+			if from <= x.key && !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return false
+			}
+			return inorder(x.right)
+		}
+		inorder(t.root)
+	}
+}
@@ -0,0 +1,314 @@
+package durable
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Tree is an rbt.RBT that can also remove keys
+type Tree[K constraints.Ordered, V any] interface {
+	rbt.RBT[K, V]
+	Delete(key K)
+}
+
+// SyncPolicy controls when the write-ahead log is flushed to stable storage
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // fsync before every Put or Delete returns
+	SyncPeriodic                   // fsync in the background every SyncInterval
+	SyncNever                      // leave flushing to the operating system
+)
+
+// Options configures a DurableRBT. the zero value syncs every write,
+// snapshots every 10000 log records and uses codec.Default encodings
+type Options[K constraints.Ordered, V any] struct {
+	Sync          SyncPolicy
+	SyncInterval  time.Duration // for SyncPeriodic, default 100ms
+	SnapshotEvery int           // log records between snapshots, < 0 disables them
+	KeyCodec      codec.Codec[K]
+	ValCodec      codec.Codec[V]
+}
+
+const (
+	snapshotFile = "snapshot"
+	walFile      = "wal"
+)
+
+var ErrClosed = errors.New("durable: tree is closed")
+
+// DurableRBT is an ordered map that survives process crashes. every Put and
+// Delete is appended to a checksummed write-ahead log before it is applied to
+// the tree, and the log is periodically folded into a sorted snapshot.
+//
+// the snapshot is replaced atomically with a rename and the log is truncated
+// afterwards. a crash between the two replays records the snapshot already
+// holds, which is harmless because replaying puts and deletes in order
+// leaves every key with the value of its last record.
+type DurableRBT[K constraints.Ordered, V any] struct {
+	mu      sync.RWMutex
+	tree    Tree[K, V]
+	dir     string
+	wal     *os.File
+	opts    Options[K, V]
+	size    int64 // bytes of intact records in the log
+	records int   // log records since the last snapshot
+	closed  bool
+	snapErr error // the last automatic snapshot failure, returned by Close
+	done    chan struct{}
+	buf     []byte
+}
+
+// open the tree stored in dir, creating dir if needed. the contents of the
+// snapshot and log are loaded into tree, which must not be used directly afterwards
+func Open[K constraints.Ordered, V any](dir string, tree Tree[K, V], opts Options[K, V]) (*DurableRBT[K, V], error) {
+	if opts.KeyCodec == nil {
+		opts.KeyCodec = codec.Default[K]()
+	}
+	if opts.ValCodec == nil {
+		opts.ValCodec = codec.Default[V]()
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = 100 * time.Millisecond
+	}
+	if opts.SnapshotEvery == 0 {
+		opts.SnapshotEvery = 10000
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	d := &DurableRBT[K, V]{tree: tree, dir: dir, opts: opts, done: make(chan struct{})}
+
+	// a leftover temporary file is a snapshot that was never renamed into place
+	os.Remove(filepath.Join(dir, snapshotFile+".tmp"))
+	if err := d.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	d.wal = wal
+	if err := d.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	if opts.Sync == SyncPeriodic {
+		go d.syncLoop()
+	}
+	return d, nil
+}
+
+func (d *DurableRBT[K, V]) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(d.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pairs, err := codec.Unmarshal(data, d.opts.KeyCodec, d.opts.ValCodec)
+	if err != nil {
+		return err
+	}
+	for _, r := range pairs {
+		d.tree.Put(r.Key, r.Val)
+	}
+	return nil
+}
+
+// insert a key-value pair, it is durable when Put returns nil under SyncAlways.
+// once the record is in the log the pair is in the tree too, even if the
+// flush that follows fails, so the tree matches what a reopen would replay
+func (d *DurableRBT[K, V]) Put(key K, val V) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.log(opPut, key, val); err != nil {
+		return err
+	}
+	d.tree.Put(key, val)
+	return d.commit()
+}
+
+// remove a key, it is durable when Delete returns nil under SyncAlways
+func (d *DurableRBT[K, V]) Delete(key K) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var zero V
+	if err := d.log(opDelete, key, zero); err != nil {
+		return err
+	}
+	d.tree.Delete(key)
+	return d.commit()
+}
+
+// flush a logged write and take a snapshot if one is due. a failed snapshot
+// does not fail the write, which is already in the log; it is retried on the
+// next write and reported by Close if none succeeds
+func (d *DurableRBT[K, V]) commit() error {
+	err := d.sync()
+	d.maybeSnapshot()
+	return err
+}
+
+func (d *DurableRBT[K, V]) Get(key K) (V, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tree.Get(key)
+}
+
+func (d *DurableRBT[K, V]) IsEmpty() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tree.IsEmpty()
+}
+
+func (d *DurableRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tree.GetAll()
+}
+
+// iterate in order while holding the read lock. the loop body must not call
+// the DurableRBT at all, a read from it deadlocks once a writer is waiting
+func (d *DurableRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		for r := range d.tree.Iterator() {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// write a snapshot of the tree now and empty the log
+func (d *DurableRBT[K, V]) Snapshot() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	if err := d.snapshot(); err != nil {
+		return err
+	}
+	d.snapErr = nil
+	return nil
+}
+
+func (d *DurableRBT[K, V]) maybeSnapshot() {
+	if d.opts.SnapshotEvery < 0 || d.records < d.opts.SnapshotEvery {
+		return
+	}
+	d.snapErr = d.snapshot()
+}
+
+func (d *DurableRBT[K, V]) snapshot() error {
+	n := 0
+	for range d.tree.Iterator() {
+		n++
+	}
+	data, err := codec.Marshal(n, d.tree.Iterator(), d.opts.KeyCodec, d.opts.ValCodec)
+	if err != nil {
+		return err
+	}
+
+	// the log must be on disk before the snapshot that replaces it
+	if err := d.wal.Sync(); err != nil {
+		return err
+	}
+	tmp := filepath.Join(d.dir, snapshotFile+".tmp")
+	if err := writeFile(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(d.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(d.dir); err != nil {
+		return err
+	}
+
+	if err := d.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := d.wal.Seek(0, 0); err != nil {
+		return err
+	}
+	d.size, d.records = 0, 0
+	return d.wal.Sync()
+}
+
+// flush the log and release the files. the tree stays readable. Close also
+// returns the error of the last automatic snapshot if it failed
+func (d *DurableRBT[K, V]) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.closed = true
+	close(d.done)
+	err := d.wal.Sync()
+	if cerr := d.wal.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = d.snapErr
+	}
+	return err
+}
+
+func (d *DurableRBT[K, V]) syncLoop() {
+	ticker := time.NewTicker(d.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			if !d.closed {
+				d.wal.Sync()
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// write a file and flush it to disk
+func writeFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// flush a directory so a rename in it survives a crash
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
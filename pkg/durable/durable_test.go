@@ -0,0 +1,265 @@
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func open(t *testing.T, dir string, opts Options[int, string]) *DurableRBT[int, string] {
+	t.Helper()
+	d, err := Open(dir, gm.NewRBT[int, string](), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// apply random puts and deletes to d and return the expected contents
+func churn(t *testing.T, d *DurableRBT[int, string], m map[int]string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		k := rand.Intn(200)
+		if rand.Intn(3) == 0 {
+			if err := d.Delete(k); err != nil {
+				t.Fatal(err)
+			}
+			delete(m, k)
+		} else {
+			v := strconv.Itoa(rand.Int())
+			if err := d.Put(k, v); err != nil {
+				t.Fatal(err)
+			}
+			m[k] = v
+		}
+	}
+}
+
+func expect(t *testing.T, d *DurableRBT[int, string], m map[int]string) {
+	t.Helper()
+	want := make([]rbt.KeyValuePair[int, string], 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		want = append(want, rbt.KeyValuePair[int, string]{Key: k, Val: m[k]})
+	}
+	if got := d.GetAll(); !slices.Equal(got, want) {
+		t.Fatalf("GetAll() = %v; want %v", got, want)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncPeriodic, SyncNever} {
+		dir := t.TempDir()
+		m := make(map[int]string)
+		opts := Options[int, string]{Sync: policy, SyncInterval: time.Millisecond, SnapshotEvery: -1}
+
+		d := open(t, dir, opts)
+		churn(t, d, m, 1000)
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Put(1, "x"); err != ErrClosed {
+			t.Errorf("Put() after Close err = %v; want %v", err, ErrClosed)
+		}
+
+		d = open(t, dir, opts)
+		expect(t, d, m)
+		churn(t, d, m, 1000)
+		d.Close()
+
+		d = open(t, dir, opts)
+		expect(t, d, m)
+		d.Close()
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	m := make(map[int]string)
+	opts := Options[int, string]{SnapshotEvery: 100}
+
+	d := open(t, dir, opts)
+	churn(t, d, m, 1050)
+	d.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Fatalf("no snapshot: %v", err)
+	}
+	// at most SnapshotEvery records are left in the log
+	if d.records >= 100 {
+		t.Errorf("%v records in the log after snapshots", d.records)
+	}
+	d = open(t, dir, opts)
+	expect(t, d, m)
+
+	if err := d.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(filepath.Join(dir, walFile)); fi.Size() != 0 {
+		t.Errorf("log size after Snapshot() = %v; want 0", fi.Size())
+	}
+	d.Close()
+
+	d = open(t, dir, opts)
+	expect(t, d, m)
+	d.Close()
+}
+
+// a crash after the snapshot rename but before the log is truncated
+// replays records the snapshot already holds
+func TestReplayOverSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	m := make(map[int]string)
+	opts := Options[int, string]{SnapshotEvery: -1}
+
+	d := open(t, dir, opts)
+	churn(t, d, m, 500)
+	wal, _ := os.ReadFile(filepath.Join(dir, walFile))
+	d.Snapshot()
+	d.Close()
+	os.WriteFile(filepath.Join(dir, walFile), wal, 0o644)
+
+	d = open(t, dir, opts)
+	expect(t, d, m)
+	d.Close()
+}
+
+func TestTornTail(t *testing.T) {
+	opts := Options[int, string]{SnapshotEvery: -1}
+	for _, damage := range []string{"cut", "flip", "garbage", "zeros"} {
+		dir := t.TempDir()
+		d := open(t, dir, opts)
+		for i := 0; i < 10; i++ {
+			d.Put(i, strconv.Itoa(i))
+		}
+		good, _ := os.ReadFile(filepath.Join(dir, walFile))
+		d.Put(10, "lost")
+		d.Close()
+
+		name := filepath.Join(dir, walFile)
+		data, _ := os.ReadFile(name)
+		switch damage {
+		case "cut":
+			data = data[:len(data)-3]
+		case "flip":
+			data[len(data)-1] ^= 1
+		case "garbage":
+			data = append(good, 0xff, 0xff, 0xff, 0x7f, 1, 2, 3, 4, 5)
+		case "zeros":
+			data = append(good, make([]byte, 4096)...)
+		}
+		os.WriteFile(name, data, 0o644)
+
+		d = open(t, dir, opts)
+		if fi, _ := os.Stat(name); fi.Size() != int64(len(good)) {
+			t.Errorf("%v: log size = %v; want %v", damage, fi.Size(), len(good))
+		}
+		if _, ok := d.Get(10); ok || len(d.GetAll()) != 10 {
+			t.Errorf("%v: GetAll() = %v", damage, d.GetAll())
+		}
+
+		// appends after recovery must be readable on the next open
+		d.Put(11, "kept")
+		d.Close()
+		d = open(t, dir, opts)
+		if v, ok := d.Get(11); !ok || v != "kept" {
+			t.Errorf("%v: Get(11) = %v, %v; want kept", damage, v, ok)
+		}
+		d.Close()
+	}
+}
+
+// damage before the last record, or a record that passes its checksum but
+// does not decode, fails Open and leaves the log as it was
+func TestCorruptLog(t *testing.T) {
+	opts := Options[int, string]{SnapshotEvery: -1}
+	for _, damage := range []string{"middle", "length", "decode", "codec"} {
+		dir := t.TempDir()
+		d := open(t, dir, opts)
+		for i := 0; i < 10; i++ {
+			d.Put(i, strconv.Itoa(i))
+		}
+		d.Close()
+
+		name := filepath.Join(dir, walFile)
+		data, _ := os.ReadFile(name)
+		reopen := opts
+		switch damage {
+		case "middle":
+			data[len(data)/2] ^= 1
+		case "length":
+			// a zero length on the sixth record, with intact records after it
+			off := 0
+			for i := 0; i < 6; i++ {
+				_, n, _ := frame(data[off:])
+				off += n
+			}
+			binary.LittleEndian.PutUint32(data[off:], 0)
+		case "decode":
+			// a put whose value length runs past the end of the payload
+			start := len(data)
+			data = append(data, make([]byte, headerSize)...)
+			data = append(data, opPut, 2, 0xff)
+			seal(data, start)
+		case "codec":
+			reopen.ValCodec = codec.Gob[string]{}
+		}
+		os.WriteFile(name, data, 0o644)
+
+		if _, err := Open(dir, gm.NewRBT[int, string](), reopen); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%v: Open() err = %v; want ErrCorrupt", damage, err)
+		}
+		if fi, _ := os.Stat(name); fi.Size() != int64(len(data)) {
+			t.Errorf("%v: log size = %v; want %v", damage, fi.Size(), len(data))
+		}
+	}
+}
+
+// a snapshot that cannot be written does not fail the write that triggered
+// it, it is retried and reported by Close
+func TestSnapshotFailure(t *testing.T) {
+	dir := t.TempDir()
+	d := open(t, dir, Options[int, string]{SnapshotEvery: 2})
+	// a directory where the temporary snapshot goes makes every snapshot fail
+	os.Mkdir(filepath.Join(dir, snapshotFile+".tmp"), 0o755)
+	for i := 0; i < 5; i++ {
+		if err := d.Put(i, strconv.Itoa(i)); err != nil {
+			t.Fatalf("Put(%v) = %v; want nil", i, err)
+		}
+	}
+	if err := d.Close(); err == nil {
+		t.Errorf("Close() after failed snapshots err = nil")
+	}
+
+	d = open(t, dir, Options[int, string]{SnapshotEvery: -1})
+	if len(d.GetAll()) != 5 {
+		t.Errorf("GetAll() = %v after reopen", d.GetAll())
+	}
+	d.Close()
+}
+
+func TestCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	d := open(t, dir, Options[int, string]{})
+	d.Put(1, "one")
+	d.Snapshot()
+	d.Close()
+
+	name := filepath.Join(dir, snapshotFile)
+	data, _ := os.ReadFile(name)
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(name, data, 0o644)
+	if _, err := Open(dir, gm.NewRBT[int, string](), Options[int, string]{}); err == nil {
+		t.Errorf("Open() with a corrupt snapshot err = nil")
+	}
+}
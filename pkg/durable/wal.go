@@ -0,0 +1,234 @@
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+)
+
+// each log record is framed as
+//
+//	length   4 bytes little endian, size of the payload
+//	checksum 4 bytes little endian, CRC-32C of the payload
+//	payload  op byte, key, and for puts the value, in the configured codecs
+//
+// the first record of a log names the key and value codecs, so a log is
+// never decoded with codecs it was not written with.
+//
+// a crash can only tear the record being appended, so on replay the log is
+// cut before a record that runs past the end of the file, a last record
+// that fails its checksum, or a tail of zeros left by the file system.
+// anything else that does not decode, such as a bad length with more data
+// after it, is corruption and Open fails rather than drop the records after it
+
+const (
+	opCodecs byte = 0
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+const headerSize = 8
+
+// records larger than this are treated as corrupt rather than allocated
+const maxRecord = 1 << 30
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCorrupt is returned by Open for a log that is damaged before its last record
+var ErrCorrupt = errors.New("durable: corrupt log")
+
+var (
+	errBadRecord = errors.New("durable: bad log record")
+	errTorn      = errors.New("durable: torn log record")
+	errSize      = errors.New("durable: bad log record size")
+	errChecksum  = errors.New("durable: log record checksum mismatch")
+)
+
+// append a record to the log. the record is in the file when log returns
+// nil, the caller applies it to the tree and then calls sync
+func (d *DurableRBT[K, V]) log(op byte, key K, val V) error {
+	if d.closed {
+		return ErrClosed
+	}
+	buf := d.buf[:0]
+	if d.size == 0 {
+		start := len(buf)
+		buf = append(buf, make([]byte, headerSize)...)
+		buf = append(buf, opCodecs)
+		buf = codec.AppendString(buf, d.opts.KeyCodec.Name())
+		buf = codec.AppendString(buf, d.opts.ValCodec.Name())
+		seal(buf, start)
+	}
+	start := len(buf)
+	buf = append(buf, make([]byte, headerSize)...)
+	buf = append(buf, op)
+	var err error
+	if buf, err = d.opts.KeyCodec.Append(buf, key); err != nil {
+		return err
+	}
+	if op == opPut {
+		if buf, err = d.opts.ValCodec.Append(buf, val); err != nil {
+			return err
+		}
+	}
+	seal(buf, start)
+	d.buf = buf
+
+	if _, err := d.wal.Write(buf); err != nil {
+		// drop a partial record so later appends are not hidden behind it
+		if d.wal.Truncate(d.size) == nil {
+			d.wal.Seek(d.size, io.SeekStart)
+		}
+		return err
+	}
+	d.size += int64(len(buf))
+	d.records++
+	return nil
+}
+
+// flush the log under SyncAlways
+func (d *DurableRBT[K, V]) sync() error {
+	if d.opts.Sync == SyncAlways {
+		return d.wal.Sync()
+	}
+	return nil
+}
+
+// fill in the size and checksum of the record starting at buf[start:]
+func seal(buf []byte, start int) {
+	payload := buf[start+headerSize:]
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[start+4:], crc32.Checksum(payload, castagnoli))
+}
+
+// apply every record in the log to the tree, then truncate a torn record at
+// the end and position the log for appending
+func (d *DurableRBT[K, V]) replay() error {
+	data, err := io.ReadAll(d.wal)
+	if err != nil {
+		return err
+	}
+
+	off := 0
+	for off < len(data) {
+		payload, n, err := frame(data[off:])
+		if err != nil {
+			if !torn(data[off:], n, err) {
+				return fmt.Errorf("%w at offset %d: %w", ErrCorrupt, off, err)
+			}
+			break
+		}
+		if err := d.apply(payload, off == 0); err != nil {
+			return fmt.Errorf("%w at offset %d: %w", ErrCorrupt, off, err)
+		}
+		off += n
+	}
+
+	if off < len(data) {
+		if err := d.wal.Truncate(int64(off)); err != nil {
+			return err
+		}
+		if err := d.wal.Sync(); err != nil {
+			return err
+		}
+	}
+	d.size = int64(off)
+	_, err = d.wal.Seek(d.size, io.SeekStart)
+	return err
+}
+
+// whether the record at the front of data that frame rejected with err can
+// be the end of an interrupted append, n is the size frame returned
+func torn(data []byte, n int, err error) bool {
+	switch {
+	case errors.Is(err, errTorn):
+		return true
+	case errors.Is(err, errChecksum):
+		return n == len(data)
+	}
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// split off the record at the front of data, returning its payload and size.
+// a record that runs past the end of data is errTorn, one with a size no
+// record can have is errSize. the size is returned with errChecksum so the
+// caller can tell whether the record was the last one
+func frame(data []byte) ([]byte, int, error) {
+	if len(data) < headerSize {
+		return nil, 0, errTorn
+	}
+	size := binary.LittleEndian.Uint32(data[0:])
+	sum := binary.LittleEndian.Uint32(data[4:])
+	if uint64(len(data)-headerSize) < uint64(size) {
+		return nil, 0, errTorn
+	}
+	if size == 0 || size > maxRecord {
+		return nil, 0, errSize
+	}
+	payload := data[headerSize : headerSize+int(size)]
+	if crc32.Checksum(payload, castagnoli) != sum {
+		return nil, headerSize + int(size), errChecksum
+	}
+	return payload, headerSize + int(size), nil
+}
+
+// decode and apply the payload of one record. first is set for the first
+// record in the log, the only place a codec record may appear
+func (d *DurableRBT[K, V]) apply(payload []byte, first bool) error {
+	op, buf := payload[0], payload[1:]
+	if op == opCodecs {
+		if !first {
+			return errBadRecord
+		}
+		for _, want := range []string{d.opts.KeyCodec.Name(), d.opts.ValCodec.Name()} {
+			name, n, err := codec.DecodeString(buf)
+			if err != nil {
+				return err
+			}
+			if name != want {
+				return fmt.Errorf("log was written with codec %q, reading with %q", name, want)
+			}
+			buf = buf[n:]
+		}
+		if len(buf) != 0 {
+			return errBadRecord
+		}
+		return nil
+	}
+
+	key, n, err := d.opts.KeyCodec.Decode(buf)
+	if err != nil {
+		return err
+	}
+	buf = buf[n:]
+	switch op {
+	case opPut:
+		val, n, err := d.opts.ValCodec.Decode(buf)
+		if err != nil {
+			return err
+		}
+		buf = buf[n:]
+		if len(buf) != 0 {
+			return errBadRecord
+		}
+		d.tree.Put(key, val)
+	case opDelete:
+		if len(buf) != 0 {
+			return errBadRecord
+		}
+		d.tree.Delete(key)
+	default:
+		return errBadRecord
+	}
+	d.records++
+	return nil
+}
@@ -0,0 +1,24 @@
+package gemini
+
+import (
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, and through it gob encoding
+func (bst *GeminiRBT[K, V]) MarshalBinary() ([]byte, error) {
+	return codec.MarshalBinary(&bst.Codecs, bst.Size(), bst.Iterator())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. it replaces the
+// contents of the tree, rebuilding it in linear time from the sorted stream.
+func (bst *GeminiRBT[K, V]) UnmarshalBinary(data []byte) error {
+	return codec.UnmarshalBinary(&bst.Codecs, data, bst.load)
+}
+
+// replace the contents of the tree with pairs sorted by key, see codec.BuildLLRB
+func (bst *GeminiRBT[K, V]) load(pairs []rbt.KeyValuePair[K, V]) {
+	bst.root = codec.BuildLLRB(pairs, func(p rbt.KeyValuePair[K, V], left, right *Node[K, V], size int, red bool) *Node[K, V] {
+		return &Node[K, V]{key: p.Key, val: p.Val, left: left, right: right, N: size, color: red}
+	})
+}
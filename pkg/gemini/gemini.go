@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
 	"sqirvy.xyz/go-tree-iterator/rbt"
 )
 
@@ -20,7 +21,11 @@ type Node[K constraints.Ordered, V any] struct {
 }
 
 type GeminiRBT[K constraints.Ordered, V any] struct {
-	root *Node[K, V]
+	codec.Codecs[K, V] // key and value codecs for MarshalBinary
+
+	root      *Node[K, V]
+	onChange  func(rbt.Event[K, V])
+	rotations int
 }
 
 func NewRBT[K constraints.Ordered, V any]() *GeminiRBT[K, V] {
@@ -66,12 +71,21 @@ func (bst *GeminiRBT[K, V]) get(x *Node[K, V], key K) *Node[K, V] {
 }
 
 func (bst *GeminiRBT[K, V]) Put(key K, val V) {
+	ev := rbt.Event[K, V]{Kind: rbt.EventPut, Key: key, New: val}
+	if bst.onChange != nil {
+		if x := bst.get(bst.root, key); x != nil {
+			ev.Kind = rbt.EventUpdate
+			ev.Old = x.val
+		}
+	}
 	bst.root = bst.put(bst.root, key, val)
+	bst.root.color = false
+	bst.notify(ev)
 }
 
 func (bst *GeminiRBT[K, V]) put(h *Node[K, V], key K, val V) *Node[K, V] {
 	if h == nil {
-		return &Node[K, V]{key: key, val: val, N: 1, color: false}
+		return &Node[K, V]{key: key, val: val, N: 1, color: true}
 	}
 	cmp := compare(key, h.key)
 	if cmp < 0 {
@@ -222,7 +236,16 @@ func (bst *GeminiRBT[K, V]) DeleteMin() {
 	if bst.IsEmpty() {
 		return
 	}
+	x := bst.min(bst.root)
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: x.key, Old: x.val}
+	if !isRed(bst.root.left) && !isRed(bst.root.right) {
+		bst.root.color = true
+	}
 	bst.root = bst.deleteMin(bst.root)
+	if !bst.IsEmpty() {
+		bst.root.color = false
+	}
+	bst.notify(ev)
 }
 
 func (bst *GeminiRBT[K, V]) deleteMin(h *Node[K, V]) *Node[K, V] {
@@ -240,7 +263,16 @@ func (bst *GeminiRBT[K, V]) DeleteMax() {
 	if bst.IsEmpty() {
 		return
 	}
+	x := bst.max(bst.root)
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: x.key, Old: x.val}
+	if !isRed(bst.root.left) && !isRed(bst.root.right) {
+		bst.root.color = true
+	}
 	bst.root = bst.deleteMax(bst.root)
+	if !bst.IsEmpty() {
+		bst.root.color = false
+	}
+	bst.notify(ev)
 }
 
 func (bst *GeminiRBT[K, V]) deleteMax(h *Node[K, V]) *Node[K, V] {
@@ -258,15 +290,30 @@ func (bst *GeminiRBT[K, V]) deleteMax(h *Node[K, V]) *Node[K, V] {
 }
 
 func (bst *GeminiRBT[K, V]) Delete(key K) {
-	if !bst.Contains(key) {
+	x := bst.get(bst.root, key)
+	if x == nil {
 		return
 	}
+	ev := rbt.Event[K, V]{Kind: rbt.EventDelete, Key: key, Old: x.val}
 	if !isRed(bst.root.left) && !isRed(bst.root.right) {
-		bst.root.color = false
+		bst.root.color = true
 	}
 	bst.root = bst.delete(bst.root, key)
 	if !bst.IsEmpty() {
-		bst.root.color = true
+		bst.root.color = false
+	}
+	bst.notify(ev)
+}
+
+// OnChange registers fn to be called after every Put, Delete, DeleteMin and
+// DeleteMax that changes the tree. nil removes the callback
+func (bst *GeminiRBT[K, V]) OnChange(fn func(rbt.Event[K, V])) {
+	bst.onChange = fn
+}
+
+func (bst *GeminiRBT[K, V]) notify(ev rbt.Event[K, V]) {
+	if bst.onChange != nil {
+		bst.onChange(ev)
 	}
 }
 
@@ -363,6 +410,11 @@ func (bst *GeminiRBT[K, V]) sizeInOrder(x *Node[K, V], lo K, hi K) int {
 	return 0
 }
 
+// the number of rotations done since the tree was created
+func (bst *GeminiRBT[K, V]) Rotations() int {
+	return bst.rotations
+}
+
 func (bst *GeminiRBT[K, V]) Height() int {
 	return bst.height(bst.root)
 }
@@ -382,6 +434,7 @@ func (bst *GeminiRBT[K, V]) IsRed(x *Node[K, V]) bool {
 }
 
 func (bst *GeminiRBT[K, V]) rotateLeft(h *Node[K, V]) *Node[K, V] {
+	bst.rotations++
 	x := h.right
 	h.right = x.left
 	x.left = h
@@ -393,6 +446,7 @@ func (bst *GeminiRBT[K, V]) rotateLeft(h *Node[K, V]) *Node[K, V] {
 }
 
 func (bst *GeminiRBT[K, V]) rotateRight(h *Node[K, V]) *Node[K, V] {
+	bst.rotations++
 	x := h.left
 	h.left = x.right
 	x.right = h
@@ -429,7 +483,7 @@ func (bst *GeminiRBT[K, V]) moveRedRight(h *Node[K, V]) *Node[K, V] {
 }
 
 func (bst *GeminiRBT[K, V]) balance(h *Node[K, V]) *Node[K, V] {
-	if isRed(h.right) {
+	if isRed(h.right) && !isRed(h.left) {
 		h = bst.rotateLeft(h)
 	}
 	if isRed(h.left) && isRed(h.left.left) {
@@ -438,6 +492,7 @@ func (bst *GeminiRBT[K, V]) balance(h *Node[K, V]) *Node[K, V] {
 	if isRed(h.left) && isRed(h.right) {
 		bst.flipColors(h)
 	}
+	h.N = 1 + bst.size(h.left) + bst.size(h.right)
 	return h
 }
 
@@ -504,16 +559,34 @@ func (bst *GeminiRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
 // gemini fix: add range over function Iterator
 func (t *GeminiRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
 	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
-		var inorder func(*Node[K, V])
-		inorder = func(n *Node[K, V]) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
 			if n == nil {
-				return
+				return true
+			}
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) &&
+				inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (t *GeminiRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			if lo < n.key && !inorder(n.left) {
+				return false
 			}
-			inorder(n.left)
-			if !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
-				return
+			if lo <= n.key && n.key <= hi && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
+				return false
 			}
-			inorder(n.right)
+			return hi <= n.key || inorder(n.right)
 		}
 		inorder(t.root)
 	}
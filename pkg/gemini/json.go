@@ -0,0 +1,14 @@
+package gemini
+
+import "sqirvy.xyz/go-tree-iterator/codec"
+
+// MarshalJSON implements json.Marshaler. a tree with string keys encodes as an
+// object with its keys in order, other trees as an array of [key, value] pairs.
+func (bst *GeminiRBT[K, V]) MarshalJSON() ([]byte, error) {
+	return codec.MarshalJSON(bst.Iterator())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it replaces the contents of the tree
+func (bst *GeminiRBT[K, V]) UnmarshalJSON(data []byte) error {
+	return codec.LoadJSON(data, bst.load)
+}
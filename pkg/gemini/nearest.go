@@ -0,0 +1,59 @@
+package gemini
+
+import (
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Nearest returns an iterator over the k entries whose keys are closest to key,
+// in increasing distance order, smaller key first on a tie. see rbt.Nearest
+func Nearest[K rbt.Number, V any](bst *GeminiRBT[K, V], key K, k int) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.Nearest(bst.neighbors(), key, k)
+}
+
+// WithinDistance returns an iterator over the entries whose keys are no more
+// than d away from key, in the same order as Nearest. see rbt.WithinDistance
+func WithinDistance[K rbt.Number, V any](bst *GeminiRBT[K, V], key K, d K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.WithinDistance(bst.neighbors(), key, d)
+}
+
+func (bst *GeminiRBT[K, V]) neighbors() rbt.Neighbors[K, V] {
+	pair := func(x *Node[K, V]) (rbt.KeyValuePair[K, V], bool) {
+		if x == nil {
+			return rbt.KeyValuePair[K, V]{}, false
+		}
+		return rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}, true
+	}
+	return rbt.Neighbors[K, V]{
+		Floor:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(bst.floor(bst.root, k)) },
+		Lower:  func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(bst.lower(bst.root, k)) },
+		Higher: func(k K) (rbt.KeyValuePair[K, V], bool) { return pair(bst.higher(bst.root, k)) },
+	}
+}
+
+// lower returns the node with the largest key strictly less than key
+func (bst *GeminiRBT[K, V]) lower(x *Node[K, V], key K) *Node[K, V] {
+	var best *Node[K, V]
+	for x != nil {
+		if compare(x.key, key) < 0 {
+			best = x
+			x = x.right
+		} else {
+			x = x.left
+		}
+	}
+	return best
+}
+
+// higher returns the node with the smallest key strictly greater than key
+func (bst *GeminiRBT[K, V]) higher(x *Node[K, V], key K) *Node[K, V] {
+	var best *Node[K, V]
+	for x != nil {
+		if compare(x.key, key) > 0 {
+			best = x
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return best
+}
@@ -0,0 +1,48 @@
+package gemini
+
+import (
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// ParallelForEach calls fn for every entry, spreading the work over workers
+// goroutines that each get a contiguous, equal range of ranks found from the
+// subtree sizes. fn is called concurrently. see rbt.ParallelForEach
+func (bst *GeminiRBT[K, V]) ParallelForEach(workers int, fn func(K, V)) {
+	rbt.ParallelForEach(bst.ranks, bst.Size(), workers, fn)
+}
+
+// ParallelReduce folds every entry into a result using workers goroutines,
+// each starting from its own init() and combined in key order.
+// see rbt.ParallelReduce
+func ParallelReduce[K constraints.Ordered, V any, T any](bst *GeminiRBT[K, V], workers int, init func() T, accumulate func(T, K, V) T, combine func(T, T) T) T {
+	return rbt.ParallelReduce(bst.ranks, bst.Size(), workers, init, accumulate, combine)
+}
+
+// ParallelMap calls fn for every entry using workers goroutines and returns
+// the results in key order. see rbt.ParallelMap
+func ParallelMap[K constraints.Ordered, V any, T any](bst *GeminiRBT[K, V], workers int, fn func(K, V) T) []T {
+	return rbt.ParallelMap(bst.ranks, bst.Size(), workers, fn)
+}
+
+// visit the entries whose rank is in [lo, hi), an rbt.Ranks
+func (bst *GeminiRBT[K, V]) ranks(lo int, hi int, visit func(K, V)) {
+	bst.rangeByRank(bst.root, 0, lo, hi, func(x *Node[K, V]) {
+		visit(x.key, x.val)
+	})
+}
+
+// rangeByRank visits, in order, the nodes of the subtree rooted at x whose
+// rank is in [lo, hi). base is the rank of the smallest key in the subtree.
+// Subtrees entirely outside the range are skipped using their size.
+func (bst *GeminiRBT[K, V]) rangeByRank(x *Node[K, V], base int, lo int, hi int, visit func(*Node[K, V])) {
+	if x == nil || base >= hi || base+x.N <= lo {
+		return
+	}
+	r := base + bst.size(x.left)
+	bst.rangeByRank(x.left, base, lo, hi, visit)
+	if r >= lo && r < hi {
+		visit(x)
+	}
+	bst.rangeByRank(x.right, r+1, lo, hi, visit)
+}
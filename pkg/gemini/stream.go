@@ -0,0 +1,27 @@
+package gemini
+
+import (
+	"io"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+)
+
+// WriteTo streams the tree to w in key order through a buffer, encoding it
+// with enc, without first collecting the pairs the way GetAll does
+func (bst *GeminiRBT[K, V]) WriteTo(w io.Writer, enc codec.Encoder[K, V]) (int64, error) {
+	return codec.WriteTo(w, enc, bst.Size(), bst.Iterator())
+}
+
+// ReadFrom creates a tree from a stream in ascending key order decoded by
+// dec, building it in linear time. it is a function rather than a method
+// because io.ReaderFrom already claims the method name with one argument
+func ReadFrom[K constraints.Ordered, V any](r io.Reader, dec codec.Decoder[K, V]) (*GeminiRBT[K, V], int64, error) {
+	pairs, n, err := codec.ReadFrom(r, dec)
+	if err != nil {
+		return nil, n, err
+	}
+	t := NewRBT[K, V]()
+	t.load(pairs)
+	return t, n, nil
+}
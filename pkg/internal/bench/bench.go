@@ -0,0 +1,100 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+
+	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// the harness the tree packages use to benchmark themselves against the
+// LLRB implementations. values are ints and their contents do not matter
+
+// Impl is a named tree implementation under comparison
+type Impl[K constraints.Ordered] struct {
+	Name string
+	New  func() rbt.RBT[K, int]
+}
+
+// LLRB returns the gemini, copilot and chatgpt trees
+func LLRB[K constraints.Ordered]() []Impl[K] {
+	return []Impl[K]{
+		{"gemini", func() rbt.RBT[K, int] { return gm.NewRBT[K, int]() }},
+		{"copilot", func() rbt.RBT[K, int] { return cp.NewRBT[K, int]() }},
+		{"chatgpt", func() rbt.RBT[K, int] { return ch.NewRBT[K, int]() }},
+	}
+}
+
+// Sizes is 10^3 to 10^7 keys, 10^7 is skipped with -short
+func Sizes() []int {
+	sizes := []int{1e3, 1e4, 1e5, 1e6, 1e7}
+	if testing.Short() {
+		sizes = sizes[:4]
+	}
+	return sizes
+}
+
+// Op is the timed part of a benchmark, run on a filled tree
+type Op[K constraints.Ordered] func(b *testing.B, t rbt.RBT[K, int], keys []K)
+
+// Workload names the keys a tree is filled with and the keys an Op is run over
+type Workload[K constraints.Ordered] struct {
+	Name string
+	Fill []K
+	Keys []K
+}
+
+// Run fills a tree of each implementation with w.Fill and times op over
+// w.Keys, as the sub-benchmark impl/w.Name
+func Run[K constraints.Ordered](b *testing.B, impls []Impl[K], w Workload[K], op Op[K]) {
+	for _, impl := range impls {
+		b.Run(fmt.Sprintf("%v/%v", impl.Name, w.Name), func(b *testing.B) {
+			t := impl.New()
+			for i, k := range w.Fill {
+				t.Put(k, i)
+			}
+			b.ResetTimer()
+			op(b, t, w.Keys)
+		})
+	}
+}
+
+// Random runs op for each of Sizes on trees filled with that many random keys
+func Random(b *testing.B, impls []Impl[int], op Op[int]) {
+	for _, n := range Sizes() {
+		keys := rand.Perm(n)
+		Run(b, impls, Workload[int]{Name: fmt.Sprintf("n=%v", n), Fill: keys, Keys: keys}, op)
+	}
+}
+
+// Get looks up keys in turn
+func Get[K constraints.Ordered](b *testing.B, t rbt.RBT[K, int], keys []K) {
+	for i := 0; i < b.N; i++ {
+		t.Get(keys[i%len(keys)])
+	}
+}
+
+// Put overwrites keys in turn
+func Put[K constraints.Ordered](b *testing.B, t rbt.RBT[K, int], keys []K) {
+	for i := 0; i < b.N; i++ {
+		t.Put(keys[i%len(keys)], i)
+	}
+}
+
+// Iterate walks the tree in order, ns/op is per key visited
+func Iterate[K constraints.Ordered](b *testing.B, t rbt.RBT[K, int], keys []K) {
+	n := 0
+	for n < b.N {
+		for range t.Iterator() {
+			if n++; n == b.N {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package lockfree
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+const red = true
+const black = false
+
+// LockFreeRBT is a left-leaning red-black tree whose readers never block.
+//
+// The root is published through an atomic.Pointer. Writers are serialized
+// by a mutex and never modify a published node: put and delete copy every
+// node on the path they change and then swap in the new root. A reader
+// loads the root once, so Get, Range and Iterator always see one complete
+// version of the tree no matter how many writes happen meanwhile.
+type LockFreeRBT[K constraints.Ordered, V any] struct {
+	root atomic.Pointer[Node[K, V]]
+	mu   sync.Mutex    // serializes publishing a new root
+	gens atomic.Uint64 // last write generation handed out
+}
+
+// Node is immutable once its root has been published
+type Node[K constraints.Ordered, V any] struct {
+	key         K
+	val         V
+	left, right *Node[K, V]
+	color       bool
+	size        int
+	gen         uint64 // write that created this copy
+	ver         uint64 // write that last set val for key
+}
+
+// create a new tree
+func NewRBT[K constraints.Ordered, V any]() *LockFreeRBT[K, V] {
+	return &LockFreeRBT[K, V]{}
+}
+
+func isRed[K constraints.Ordered, V any](x *Node[K, V]) bool {
+	if x == nil {
+		return false
+	}
+	return x.color
+}
+
+func size[K constraints.Ordered, V any](x *Node[K, V]) int {
+	if x == nil {
+		return 0
+	}
+	return x.size
+}
+
+// ************ readers ************
+
+// get the number of key-value pairs
+func (t *LockFreeRBT[K, V]) Size() int {
+	return size(t.root.Load())
+}
+
+// check if the tree is empty
+func (t *LockFreeRBT[K, V]) IsEmpty() bool {
+	return t.root.Load() == nil
+}
+
+// get the value of a key
+func (t *LockFreeRBT[K, V]) Get(key K) (V, bool) {
+	x := get(t.root.Load(), key)
+	if x == nil {
+		var zero V
+		return zero, false
+	}
+	return x.val, true
+}
+
+func get[K constraints.Ordered, V any](x *Node[K, V], key K) *Node[K, V] {
+	for x != nil {
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			x = x.right
+		} else {
+			return x
+		}
+	}
+	return nil
+}
+
+// get all key-value pairs in order
+func (t *LockFreeRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	root := t.root.Load()
+	pairs := make([]rbt.KeyValuePair[K, V], 0, size(root))
+	for r := range iterate(root) {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+// iterate over the key-value pairs in order
+func (t *LockFreeRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		iterate(t.root.Load())(yield)
+	}
+}
+
+// iterate over the key-value pairs with keys in [lo..hi] in order
+func (t *LockFreeRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		iterateRange(t.root.Load(), lo, hi)(yield)
+	}
+}
+
+func iterate[K constraints.Ordered, V any](root *Node[K, V]) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) &&
+				inorder(n.right)
+		}
+		inorder(root)
+	}
+}
+
+func iterateRange[K constraints.Ordered, V any](root *Node[K, V], lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(x *Node[K, V]) bool {
+			if x == nil {
+				return true
+			}
+			if lo < x.key && !inorder(x.left) {
+				return false
+			}
+			if lo <= x.key && hi >= x.key && !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return false
+			}
+			if hi > x.key {
+				return inorder(x.right)
+			}
+			return true
+		}
+		inorder(root)
+	}
+}
+
+// ************ writers ************
+
+// insert a key-value pair and publish the new version
+func (t *LockFreeRBT[K, V]) Put(key K, val V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.Store(t.writer().insert(t.root.Load(), key, val))
+}
+
+// remove a key and publish the new version
+func (t *LockFreeRBT[K, V]) Delete(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.Store(t.writer().remove(t.root.Load(), key))
+}
+
+// writer builds one new version of the tree. It may modify the nodes it
+// created itself, which are marked with its generation, and copies any
+// other node before changing it.
+type writer[K constraints.Ordered, V any] struct {
+	gen uint64
+}
+
+// start a new write with a generation no other write has used
+func (t *LockFreeRBT[K, V]) writer() *writer[K, V] {
+	return &writer[K, V]{gen: t.gens.Add(1)}
+}
+
+// insert a key-value pair into the version rooted at root and return the new root
+func (w *writer[K, V]) insert(root *Node[K, V], key K, val V) *Node[K, V] {
+	root = w.put(root, key, val)
+	root.color = black
+	return root
+}
+
+// remove a key from the version rooted at root and return the new root
+func (w *writer[K, V]) remove(root *Node[K, V], key K) *Node[K, V] {
+	if get(root, key) == nil {
+		return root
+	}
+	root = w.own(root)
+	if !isRed(root.left) && !isRed(root.right) {
+		root.color = red
+	}
+	root = w.delete(root, key)
+	if root != nil {
+		root.color = black
+	}
+	return root
+}
+
+func (w *writer[K, V]) put(h *Node[K, V], key K, val V) *Node[K, V] {
+	if h == nil {
+		return &Node[K, V]{key: key, val: val, color: red, size: 1, gen: w.gen, ver: w.gen}
+	}
+	h = w.own(h)
+	if key < h.key {
+		h.left = w.put(h.left, key, val)
+	} else if key > h.key {
+		h.right = w.put(h.right, key, val)
+	} else {
+		h.val = val
+		h.ver = w.gen
+	}
+	return w.balance(h)
+}
+
+func (w *writer[K, V]) delete(h *Node[K, V], key K) *Node[K, V] {
+	h = w.own(h)
+	if key < h.key {
+		if !isRed(h.left) && !isRed(h.left.left) {
+			h = w.moveRedLeft(h)
+		}
+		h.left = w.delete(h.left, key)
+	} else {
+		if isRed(h.left) {
+			h = w.rotateRight(h)
+		}
+		if key == h.key && h.right == nil {
+			return nil
+		}
+		if !isRed(h.right) && !isRed(h.right.left) {
+			h = w.moveRedRight(h)
+		}
+		if key == h.key {
+			x := h.right
+			for x.left != nil {
+				x = x.left
+			}
+			h.key = x.key
+			h.val = x.val
+			h.ver = x.ver
+			h.right = w.deleteMin(h.right)
+		} else {
+			h.right = w.delete(h.right, key)
+		}
+	}
+	return w.balance(h)
+}
+
+func (w *writer[K, V]) deleteMin(h *Node[K, V]) *Node[K, V] {
+	if h.left == nil {
+		return nil
+	}
+	h = w.own(h)
+	if !isRed(h.left) && !isRed(h.left.left) {
+		h = w.moveRedLeft(h)
+	}
+	h.left = w.deleteMin(h.left)
+	return w.balance(h)
+}
+
+// ************ path copying helpers ************
+
+// own returns a node that the current write may modify: h itself if this
+// write created it, otherwise a copy of h
+func (w *writer[K, V]) own(h *Node[K, V]) *Node[K, V] {
+	if h == nil || h.gen == w.gen {
+		return h
+	}
+	c := *h
+	c.gen = w.gen
+	return &c
+}
+
+// the helpers below take an owned h and own any child they modify
+
+func (w *writer[K, V]) rotateLeft(h *Node[K, V]) *Node[K, V] {
+	x := w.own(h.right)
+	h.right = x.left
+	x.left = h
+	x.color = h.color
+	h.color = red
+	x.size = h.size
+	h.size = 1 + size(h.left) + size(h.right)
+	return x
+}
+
+func (w *writer[K, V]) rotateRight(h *Node[K, V]) *Node[K, V] {
+	x := w.own(h.left)
+	h.left = x.right
+	x.right = h
+	x.color = h.color
+	h.color = red
+	x.size = h.size
+	h.size = 1 + size(h.left) + size(h.right)
+	return x
+}
+
+func (w *writer[K, V]) flipColors(h *Node[K, V]) {
+	h.left = w.own(h.left)
+	h.right = w.own(h.right)
+	h.color = !h.color
+	h.left.color = !h.left.color
+	h.right.color = !h.right.color
+}
+
+func (w *writer[K, V]) moveRedLeft(h *Node[K, V]) *Node[K, V] {
+	w.flipColors(h)
+	if isRed(h.right.left) {
+		h.right = w.rotateRight(h.right)
+		h = w.rotateLeft(h)
+		w.flipColors(h)
+	}
+	return h
+}
+
+func (w *writer[K, V]) moveRedRight(h *Node[K, V]) *Node[K, V] {
+	w.flipColors(h)
+	if isRed(h.left.left) {
+		h = w.rotateRight(h)
+		w.flipColors(h)
+	}
+	return h
+}
+
+func (w *writer[K, V]) balance(h *Node[K, V]) *Node[K, V] {
+	if isRed(h.right) && !isRed(h.left) {
+		h = w.rotateLeft(h)
+	}
+	if isRed(h.left) && isRed(h.left.left) {
+		h = w.rotateRight(h)
+	}
+	if isRed(h.left) && isRed(h.right) {
+		w.flipColors(h)
+	}
+	h.size = 1 + size(h.left) + size(h.right)
+	return h
+}
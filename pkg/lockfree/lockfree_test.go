@@ -0,0 +1,312 @@
+package lockfree
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEmptyRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+	if v, ok := rbt.Get(1); ok {
+		t.Errorf("Get(1) = %v; want false", v)
+	}
+	rbt.Delete(1)
+}
+
+// test Put, Get and Delete against a map with random keys
+func TestRandomOps(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	m := make(map[int]int)
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(1000)
+		if rand.Intn(3) == 0 {
+			rbt.Delete(k)
+			delete(m, k)
+		} else {
+			rbt.Put(k, i)
+			m[k] = i
+		}
+		if rbt.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", rbt.Size(), len(m))
+		}
+	}
+	for k, v := range m {
+		if x, ok := rbt.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v, %v; want %v", k, x, ok, v)
+		}
+	}
+
+	k := -1
+	for r := range rbt.Iterator() {
+		if r.Key <= k {
+			t.Errorf("Out of order(%v) = %v; ", k, r.Key)
+		}
+		k = r.Key
+	}
+}
+
+// test that a range in progress keeps seeing the version it started with
+func TestIteratorVersion(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	for i := 0; i < 100; i++ {
+		rbt.Put(i, i)
+	}
+
+	got := make([]int, 0)
+	for r := range rbt.Iterator() {
+		got = append(got, r.Key)
+		// rewrite the tree from inside the range
+		rbt.Delete(r.Key + 1)
+		rbt.Put(r.Key+1000, 0)
+	}
+	if len(got) != 100 {
+		t.Errorf("Iterator() visited %v keys; want 100", len(got))
+	}
+	// 0 and 1000..1099 are left
+	if rbt.Size() != 101 {
+		t.Errorf("Size() = %v; want 101", rbt.Size())
+	}
+
+	want := []int{10, 11, 12}
+	got = got[:0]
+	for r := range rbt.Range(1010, 1012) {
+		got = append(got, r.Key-1000)
+		rbt.Put(r.Key+1, 0)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Range(1010, 1012) = %v; want %v", got, want)
+	}
+}
+
+// readers must always see a prefix 0..n-1 while a writer appends keys
+// in order and another writer churns keys above the prefix
+func TestConcurrentReaders(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	var done atomic.Bool
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer done.Store(true)
+		for i := 0; i < 3000; i++ {
+			rbt.Put(i, i)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !done.Load() {
+			k := 100000 + rand.Intn(100)
+			rbt.Put(k, k)
+			rbt.Delete(k)
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				n := 0
+				for x := range rbt.Range(0, 99999) {
+					if x.Key != n || x.Val != n {
+						t.Errorf("Range() = %v at %v", x, n)
+						return
+					}
+					n++
+				}
+				if v, ok := rbt.Get(n); ok && v != n {
+					t.Errorf("Get(%v) = %v", n, v)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if rbt.Size() != 3000 {
+		t.Errorf("Size() = %v; want 3000", rbt.Size())
+	}
+}
+
+// test that a transaction reads its own writes and nobody else does until Commit
+func TestTxnCommit(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+
+	tx := rbt.Begin()
+	tx.Put(3, "three")
+	tx.Delete(1)
+	tx.Put(2, "TWO")
+
+	if v, ok := tx.Get(3); !ok || v != "three" {
+		t.Errorf("tx.Get(3) = %v, %v; want three, true", v, ok)
+	}
+	if v, ok := tx.Get(1); ok {
+		t.Errorf("tx.Get(1) = %v; want false", v)
+	}
+	got := make([]int, 0)
+	for r := range tx.Range(0, 10) {
+		got = append(got, r.Key)
+	}
+	if want := []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("tx.Range() = %v; want %v", got, want)
+	}
+
+	if v, ok := rbt.Get(2); !ok || v != "two" {
+		t.Errorf("Get(2) = %v before Commit; want two", v)
+	}
+	if _, ok := rbt.Get(3); ok {
+		t.Errorf("Get(3) found before Commit")
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() = %v", err)
+	}
+	if v, _ := rbt.Get(2); v != "TWO" {
+		t.Errorf("Get(2) = %v after Commit; want TWO", v)
+	}
+	if _, ok := rbt.Get(1); ok {
+		t.Errorf("Get(1) found after Commit")
+	}
+	if err := tx.Commit(); err != ErrTxnDone {
+		t.Errorf("second Commit() = %v; want ErrTxnDone", err)
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+
+	tx := rbt.Begin()
+	tx.Put(1, "ONE")
+	tx.Put(2, "two")
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() = %v", err)
+	}
+	if v, _ := rbt.Get(1); v != "one" || rbt.Size() != 1 {
+		t.Errorf("tree changed by a rolled back transaction")
+	}
+	if err := tx.Commit(); err != ErrTxnDone {
+		t.Errorf("Commit() after Rollback = %v; want ErrTxnDone", err)
+	}
+}
+
+// test first-committer-wins on a shared key and rebasing of disjoint writes
+func TestTxnConflict(t *testing.T) {
+	rbt := NewRBT[int, int]()
+	for i := 0; i < 10; i++ {
+		rbt.Put(i, i)
+	}
+
+	a := rbt.Begin()
+	b := rbt.Begin()
+	c := rbt.Begin()
+	v, _ := a.Get(5)
+	a.Put(5, v+1)
+	v, _ = b.Get(5)
+	b.Put(5, v+1)
+	c.Put(7, 70)
+	c.Delete(8)
+
+	if err := a.Commit(); err != nil {
+		t.Fatalf("a.Commit() = %v", err)
+	}
+	if err := b.Commit(); err != ErrConflict {
+		t.Errorf("b.Commit() = %v; want ErrConflict", err)
+	}
+	if err := c.Commit(); err != nil {
+		t.Errorf("c.Commit() = %v; want nil", err)
+	}
+
+	if v, _ := rbt.Get(5); v != 6 {
+		t.Errorf("Get(5) = %v; want 6", v)
+	}
+	if v, _ := rbt.Get(7); v != 70 {
+		t.Errorf("Get(7) = %v; want 70", v)
+	}
+	if _, ok := rbt.Get(8); ok {
+		t.Errorf("Get(8) found after delete")
+	}
+
+	// a plain Put also counts as a conflicting write
+	d := rbt.Begin()
+	d.Get(1)
+	d.Put(2, 0)
+	rbt.Put(1, 100)
+	if err := d.Commit(); err != ErrConflict {
+		t.Errorf("d.Commit() = %v; want ErrConflict", err)
+	}
+}
+
+// concurrent transfers between accounts keep the total constant, and
+// readers never see a half-applied transfer
+func TestTxnAtomic(t *testing.T) {
+	const accounts = 10
+	rbt := NewRBT[int, int]()
+	for i := 0; i < accounts; i++ {
+		rbt.Put(i, 100)
+	}
+
+	var done atomic.Bool
+	var reader sync.WaitGroup
+	reader.Add(1)
+	go func() {
+		defer reader.Done()
+		for !done.Load() {
+			total := 0
+			for r := range rbt.Iterator() {
+				total += r.Val
+			}
+			if total != accounts*100 {
+				t.Errorf("total = %v; want %v", total, accounts*100)
+				return
+			}
+		}
+	}()
+
+	var writers sync.WaitGroup
+	var committed, conflicts atomic.Int64
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for i := 0; i < 500; i++ {
+				from, to := rand.Intn(accounts), rand.Intn(accounts)
+				if from == to {
+					continue
+				}
+				tx := rbt.Begin()
+				a, _ := tx.Get(from)
+				b, _ := tx.Get(to)
+				tx.Put(from, a-1)
+				tx.Put(to, b+1)
+				if err := tx.Commit(); err == nil {
+					committed.Add(1)
+				} else {
+					conflicts.Add(1)
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	done.Store(true)
+	reader.Wait()
+
+	total := 0
+	for r := range rbt.Iterator() {
+		total += r.Val
+	}
+	if total != accounts*100 {
+		t.Errorf("total = %v; want %v", total, accounts*100)
+	}
+	t.Log("committed", committed.Load(), "conflicts", conflicts.Load())
+}
@@ -0,0 +1,157 @@
+package lockfree
+
+import (
+	"errors"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// ErrConflict is returned by Commit when another commit changed a key the
+// transaction depends on
+var ErrConflict = errors.New("lockfree: transaction conflicts with a concurrent commit")
+
+// ErrTxnDone is returned when a transaction is used after Commit or Rollback
+var ErrTxnDone = errors.New("lockfree: transaction already committed or rolled back")
+
+// Txn is a batch of reads and writes that other readers see all at once.
+//
+// A transaction reads the version of the tree that was current at Begin
+// plus its own writes, which it applies to a private copy of the paths
+// they change. Commit publishes the result with a single root swap. If
+// another commit has landed since Begin, Commit checks every key the
+// transaction read with Get or wrote: if any of them was changed it
+// returns ErrConflict and publishes nothing, otherwise it replays the
+// writes on top of the current version. Keys seen only through Range or
+// Iterator are not checked. A Txn is not safe for concurrent use.
+type Txn[K constraints.Ordered, V any] struct {
+	t      *LockFreeRBT[K, V]
+	w      *writer[K, V]
+	base   *Node[K, V] // version at Begin
+	root   *Node[K, V] // base plus this transaction's writes
+	reads  map[K]struct{}
+	writes map[K]txnWrite[V] // last write to each key
+	done   bool
+}
+
+type txnWrite[V any] struct {
+	val     V
+	deleted bool
+}
+
+// start a transaction on the current version of the tree
+func (t *LockFreeRBT[K, V]) Begin() *Txn[K, V] {
+	root := t.root.Load()
+	return &Txn[K, V]{
+		t:      t,
+		w:      t.writer(),
+		base:   root,
+		root:   root,
+		reads:  make(map[K]struct{}),
+		writes: make(map[K]txnWrite[V]),
+	}
+}
+
+// get the value of a key as seen by this transaction
+func (tx *Txn[K, V]) Get(key K) (V, bool) {
+	tx.reads[key] = struct{}{}
+	x := get(tx.root, key)
+	if x == nil {
+		var zero V
+		return zero, false
+	}
+	return x.val, true
+}
+
+// insert a key-value pair. it has no effect after Commit or Rollback
+func (tx *Txn[K, V]) Put(key K, val V) {
+	if tx.done {
+		return
+	}
+	tx.root = tx.w.insert(tx.root, key, val)
+	tx.writes[key] = txnWrite[V]{val: val}
+}
+
+// remove a key. it has no effect after Commit or Rollback
+func (tx *Txn[K, V]) Delete(key K) {
+	if tx.done {
+		return
+	}
+	tx.root = tx.w.remove(tx.root, key)
+	tx.writes[key] = txnWrite[V]{deleted: true}
+}
+
+// iterate over the key-value pairs seen by this transaction in order
+func (tx *Txn[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return iterate(tx.root)
+}
+
+// iterate over the key-value pairs with keys in [lo..hi] seen by this transaction
+func (tx *Txn[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return iterateRange(tx.root, lo, hi)
+}
+
+// publish the transaction's writes atomically
+func (tx *Txn[K, V]) Commit() error {
+	if tx.done {
+		return ErrTxnDone
+	}
+	tx.done = true
+	if len(tx.writes) == 0 {
+		return nil
+	}
+
+	t := tx.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := t.root.Load()
+	if current == tx.base {
+		t.root.Store(tx.root)
+		return nil
+	}
+
+	for k := range tx.reads {
+		if changed(tx.base, current, k) {
+			return ErrConflict
+		}
+	}
+	for k := range tx.writes {
+		if changed(tx.base, current, k) {
+			return ErrConflict
+		}
+	}
+
+	w := t.writer()
+	root := current
+	for k, op := range tx.writes {
+		if op.deleted {
+			root = w.remove(root, k)
+		} else {
+			root = w.insert(root, k, op.val)
+		}
+	}
+	t.root.Store(root)
+	return nil
+}
+
+// discard the transaction's writes
+func (tx *Txn[K, V]) Rollback() error {
+	if tx.done {
+		return ErrTxnDone
+	}
+	tx.done = true
+	tx.root = tx.base
+	return nil
+}
+
+// check if key was inserted, removed or overwritten between two versions
+func changed[K constraints.Ordered, V any](from *Node[K, V], to *Node[K, V], key K) bool {
+	a := get(from, key)
+	b := get(to, key)
+	if a == nil || b == nil {
+		return a != b
+	}
+	return a.ver != b.ver
+}
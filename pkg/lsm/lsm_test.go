@@ -0,0 +1,187 @@
+package lsm
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func flush(t *testing.T, m *Memtable[int, string]) *Table[int, string] {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := m.FlushToSSTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	table, err := OpenTable[int, string](bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func collect(t *testing.T, it func(func(rbt.KeyValuePair[int, string], error) bool)) []rbt.KeyValuePair[int, string] {
+	t.Helper()
+	var pairs []rbt.KeyValuePair[int, string]
+	for r, err := range it {
+		if err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func expected(m map[int]string, lo, hi int) []rbt.KeyValuePair[int, string] {
+	var pairs []rbt.KeyValuePair[int, string]
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		if lo <= k && k <= hi {
+			pairs = append(pairs, rbt.KeyValuePair[int, string]{Key: k, Val: m[k]})
+		}
+	}
+	return pairs
+}
+
+func TestTable(t *testing.T) {
+	m := NewMemtable[int, string]()
+	for i := 0; i < 10000; i += 2 {
+		m.Put(i, strconv.Itoa(i))
+	}
+	m.Delete(100)
+	table := flush(t, m)
+
+	if table.Len() != 5000 || len(table.index) < 2 {
+		t.Fatalf("Len() = %v with %v blocks", table.Len(), len(table.index))
+	}
+	for _, k := range []int{0, 2, 4998, 9998} {
+		if r, ok, err := table.Get(k); !ok || err != nil || r.Val != strconv.Itoa(k) || r.Deleted {
+			t.Errorf("Get(%v) = %v, %v, %v", k, r, ok, err)
+		}
+	}
+	for _, k := range []int{-1, 1, 4999, 10000} {
+		if r, ok, err := table.Get(k); ok || err != nil {
+			t.Errorf("Get(%v) = %v, %v, %v; want not found", k, r, ok, err)
+		}
+	}
+	if r, ok, _ := table.Get(100); !ok || !r.Deleted {
+		t.Errorf("Get(100) = %v, %v; want a tombstone", r, ok)
+	}
+
+	var keys []int
+	for r, err := range table.Range(95, 105) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, r.Key)
+	}
+	if !slices.Equal(keys, []int{96, 98, 100, 102, 104}) {
+		t.Errorf("Range(95, 105) = %v", keys)
+	}
+}
+
+func TestTableCorrupt(t *testing.T) {
+	m := NewMemtable[int, string]()
+	for i := 0; i < 5000; i++ {
+		m.Put(i, strconv.Itoa(i))
+	}
+	var buf bytes.Buffer
+	m.FlushToSSTable(&buf)
+	data := buf.Bytes()
+
+	// damage the first data block, the index still opens
+	data[10] ^= 0xff
+	table, err := OpenTable[int, string](bytes.NewReader(data), int64(len(data)), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := table.Get(1); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Get() in a damaged block err = %v; want %v", err, ErrChecksum)
+	}
+	if _, ok, err := table.Get(4999); !ok || err != nil {
+		t.Errorf("Get() in an intact block = %v, %v", ok, err)
+	}
+
+	data[len(data)-footerSize-1] ^= 0xff
+	if _, err := OpenTable[int, string](bytes.NewReader(data), int64(len(data)), nil, nil); !errors.Is(err, ErrChecksum) {
+		t.Errorf("OpenTable() with a damaged index err = %v; want %v", err, ErrChecksum)
+	}
+	if _, err := OpenTable[int, string](bytes.NewReader(data[:10]), 10, nil, nil); !errors.Is(err, ErrFormat) {
+		t.Errorf("OpenTable() of a short file err = %v; want %v", err, ErrFormat)
+	}
+}
+
+func TestView(t *testing.T) {
+	m := make(map[int]string)
+	var tables []*Table[int, string]
+	mem := NewMemtable[int, string]()
+
+	for round := 0; round < 5; round++ {
+		mem = NewMemtable[int, string]()
+		for i := 0; i < 2000; i++ {
+			k := rand.Intn(3000)
+			if rand.Intn(4) == 0 {
+				mem.Delete(k)
+				delete(m, k)
+			} else {
+				v := strconv.Itoa(rand.Int())
+				mem.Put(k, v)
+				m[k] = v
+			}
+		}
+		if round < 4 {
+			tables = append([]*Table[int, string]{flush(t, mem)}, tables...)
+		}
+	}
+
+	view := NewView(mem, tables...)
+	for k := -1; k <= 3000; k++ {
+		want, wok := m[k]
+		if v, ok, err := view.Get(k); v != want || ok != wok || err != nil {
+			t.Fatalf("Get(%v) = %v, %v, %v; want %v, %v", k, v, ok, err, want, wok)
+		}
+	}
+	if got, want := collect(t, view.Iterator()), expected(m, -1, 3000); !slices.Equal(got, want) {
+		t.Errorf("Iterator() has %v pairs; want %v", len(got), len(want))
+	}
+	if got, want := collect(t, view.Range(1000, 1100)), expected(m, 1000, 1100); !slices.Equal(got, want) {
+		t.Errorf("Range(1000, 1100) = %v; want %v", got, want)
+	}
+
+	// compacting the flushed tables keeps tombstones that hide older tables,
+	// dropping them is safe once the output replaces every table
+	tables = append([]*Table[int, string]{flush(t, mem)}, tables...)
+	for _, drop := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Compact(&buf, drop, tables...); err != nil {
+			t.Fatal(err)
+		}
+		compacted, err := OpenTable[int, string](bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := collect(t, NewView(nil, compacted).Iterator()), expected(m, -1, 3000); !slices.Equal(got, want) {
+			t.Errorf("compacted(drop = %v) has %v pairs; want %v", drop, len(got), len(want))
+		}
+		if drop && compacted.Len() != len(m) {
+			t.Errorf("compacted.Len() = %v; want %v live records", compacted.Len(), len(m))
+		}
+		if !drop && compacted.Len() <= len(m) {
+			t.Errorf("compacted.Len() = %v; want tombstones kept", compacted.Len())
+		}
+	}
+}
+
+func TestWriteOrder(t *testing.T) {
+	unsorted := func(yield func(Record[int, string], error) bool) {
+		_ = yield(Record[int, string]{Key: 2}, nil) && yield(Record[int, string]{Key: 1}, nil)
+	}
+	m := NewMemtable[int, string]()
+	if err := writeTable(&bytes.Buffer{}, unsorted, m.keyCodec, m.valCodec); !errors.Is(err, ErrOrder) {
+		t.Errorf("writeTable(unsorted) err = %v; want %v", err, ErrOrder)
+	}
+}
@@ -0,0 +1,109 @@
+package lsm
+
+import (
+	"io"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+)
+
+// Record is a key with its value, or a tombstone recording that the key
+// was deleted. tombstones hide older values of the key in other tables
+type Record[K constraints.Ordered, V any] struct {
+	Key     K
+	Val     V
+	Deleted bool
+}
+
+// entry is the memtable value, a tombstone has deleted set
+type entry[V any] struct {
+	val     V
+	deleted bool
+}
+
+// Memtable is the mutable, in-memory level of an LSM store: a gemini tree
+// that keeps tombstones for deleted keys until it is flushed to an SSTable
+type Memtable[K constraints.Ordered, V any] struct {
+	tree     *gm.GeminiRBT[K, entry[V]]
+	keyCodec codec.Codec[K]
+	valCodec codec.Codec[V]
+}
+
+// create a memtable that writes tables with codec.Default encodings
+func NewMemtable[K constraints.Ordered, V any]() *Memtable[K, V] {
+	return &Memtable[K, V]{
+		tree:     gm.NewRBT[K, entry[V]](),
+		keyCodec: codec.Default[K](),
+		valCodec: codec.Default[V](),
+	}
+}
+
+// select the encodings used by FlushToSSTable. a nil codec keeps the default
+func (m *Memtable[K, V]) SetCodecs(kc codec.Codec[K], vc codec.Codec[V]) {
+	if kc != nil {
+		m.keyCodec = kc
+	}
+	if vc != nil {
+		m.valCodec = vc
+	}
+}
+
+func (m *Memtable[K, V]) Put(key K, val V) {
+	m.tree.Put(key, entry[V]{val: val})
+}
+
+// record a tombstone for key
+func (m *Memtable[K, V]) Delete(key K) {
+	m.tree.Put(key, entry[V]{deleted: true})
+}
+
+// get the value of a key, false if it is absent or deleted
+func (m *Memtable[K, V]) Get(key K) (V, bool) {
+	e, ok := m.tree.Get(key)
+	return e.val, ok && !e.deleted
+}
+
+// the number of records, tombstones included
+func (m *Memtable[K, V]) Size() int {
+	return m.tree.Size()
+}
+
+func (m *Memtable[K, V]) IsEmpty() bool {
+	return m.tree.IsEmpty()
+}
+
+// look up the record for key, which may be a tombstone
+func (m *Memtable[K, V]) lookup(key K) (Record[K, V], bool) {
+	e, ok := m.tree.Get(key)
+	return Record[K, V]{Key: key, Val: e.val, Deleted: e.deleted}, ok
+}
+
+// iterate over the records in [lo..hi] in key order, tombstones included
+func (m *Memtable[K, V]) Range(lo K, hi K) func(func(Record[K, V], error) bool) {
+	return func(yield func(Record[K, V], error) bool) {
+		for r := range m.tree.Range(lo, hi) {
+			if !yield(Record[K, V]{Key: r.Key, Val: r.Val.val, Deleted: r.Val.deleted}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// iterate over all records in key order, tombstones included
+func (m *Memtable[K, V]) Iterator() func(func(Record[K, V], error) bool) {
+	return func(yield func(Record[K, V], error) bool) {
+		for r := range m.tree.Iterator() {
+			if !yield(Record[K, V]{Key: r.Key, Val: r.Val.val, Deleted: r.Val.deleted}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// write the memtable, tombstones included, as an immutable sorted table.
+// the memtable is left unchanged, callers usually replace it with a new one
+func (m *Memtable[K, V]) FlushToSSTable(w io.Writer) error {
+	return writeTable(w, m.Iterator(), m.keyCodec, m.valCodec)
+}
@@ -0,0 +1,327 @@
+package lsm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+)
+
+// an SSTable is laid out as
+//
+//	blocks   records in key order, cut into blocks of about BlockSize bytes.
+//	         a record is a flags byte (1 for a tombstone), the key and, for
+//	         live records, the value. each block is followed by its CRC-32C
+//	index    key codec name, value codec name, record count, block count, then
+//	         for each block its first key, offset and length. one key per
+//	         block keeps the index small enough to hold in memory
+//	footer   index offset and length (8 bytes each), index CRC-32C (4 bytes),
+//	         version (1 byte) and magic "GTSS", all little endian
+
+// BlockSize is the size a data block grows to before it is cut
+const BlockSize = 4096
+
+const Version = 1
+
+const footerSize = 8 + 8 + 4 + 1 + 4
+
+var magic = []byte("GTSS")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	ErrFormat   = errors.New("lsm: not an sstable")
+	ErrVersion  = errors.New("lsm: unsupported sstable version")
+	ErrChecksum = errors.New("lsm: sstable checksum mismatch")
+	ErrOrder    = errors.New("lsm: records are not in ascending key order")
+)
+
+// blockHandle locates one data block
+type blockHandle[K constraints.Ordered] struct {
+	first  K
+	offset int64
+	length int
+}
+
+// write the records produced by it, which must be in strictly ascending key order
+func writeTable[K constraints.Ordered, V any](w io.Writer, it func(func(Record[K, V], error) bool), kc codec.Codec[K], vc codec.Codec[V]) error {
+	bw := bufio.NewWriter(w)
+	var (
+		index  []blockHandle[K]
+		block  []byte
+		offset int64
+		count  int
+		last   K
+		err    error
+	)
+
+	cut := func() error {
+		if len(block) == 0 {
+			return nil
+		}
+		index[len(index)-1].length = len(block)
+		block = binary.LittleEndian.AppendUint32(block, crc32.Checksum(block, castagnoli))
+		if _, err := bw.Write(block); err != nil {
+			return err
+		}
+		offset += int64(len(block))
+		block = block[:0]
+		return nil
+	}
+
+	for r, rerr := range it {
+		if rerr != nil {
+			return rerr
+		}
+		if count > 0 && !(last < r.Key) {
+			return ErrOrder
+		}
+		if len(block) == 0 {
+			index = append(index, blockHandle[K]{first: r.Key, offset: offset})
+		}
+		if r.Deleted {
+			block = append(block, 1)
+			block, err = kc.Append(block, r.Key)
+		} else {
+			block = append(block, 0)
+			if block, err = kc.Append(block, r.Key); err == nil {
+				block, err = vc.Append(block, r.Val)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		last = r.Key
+		count++
+		if len(block) >= BlockSize {
+			if err := cut(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cut(); err != nil {
+		return err
+	}
+
+	idx := codec.AppendString(nil, kc.Name())
+	idx = codec.AppendString(idx, vc.Name())
+	idx = binary.AppendUvarint(idx, uint64(count))
+	idx = binary.AppendUvarint(idx, uint64(len(index)))
+	for _, h := range index {
+		if idx, err = kc.Append(idx, h.first); err != nil {
+			return err
+		}
+		idx = binary.AppendUvarint(idx, uint64(h.offset))
+		idx = binary.AppendUvarint(idx, uint64(h.length))
+	}
+
+	footer := binary.LittleEndian.AppendUint64(nil, uint64(offset))
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(len(idx)))
+	footer = binary.LittleEndian.AppendUint32(footer, crc32.Checksum(idx, castagnoli))
+	footer = append(footer, Version)
+	footer = append(footer, magic...)
+
+	if _, err := bw.Write(idx); err != nil {
+		return err
+	}
+	if _, err := bw.Write(footer); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Table reads an SSTable. the sparse index is loaded when the table is
+// opened, data blocks are read and checked on demand
+type Table[K constraints.Ordered, V any] struct {
+	r     io.ReaderAt
+	kc    codec.Codec[K]
+	vc    codec.Codec[V]
+	index []blockHandle[K]
+	count int
+}
+
+// open the table of the given size in r. nil codecs select codec.Default,
+// they must match the codecs the table was written with
+func OpenTable[K constraints.Ordered, V any](r io.ReaderAt, size int64, kc codec.Codec[K], vc codec.Codec[V]) (*Table[K, V], error) {
+	if kc == nil {
+		kc = codec.Default[K]()
+	}
+	if vc == nil {
+		vc = codec.Default[V]()
+	}
+	if size < footerSize {
+		return nil, ErrFormat
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-footerSize); err != nil {
+		return nil, err
+	}
+	if string(footer[footerSize-len(magic):]) != string(magic) {
+		return nil, ErrFormat
+	}
+	if footer[20] != Version {
+		return nil, ErrVersion
+	}
+	off := binary.LittleEndian.Uint64(footer[0:])
+	length := binary.LittleEndian.Uint64(footer[8:])
+	if off > uint64(size) || length > uint64(size)-off || off+length != uint64(size-footerSize) {
+		return nil, ErrFormat
+	}
+	idx := make([]byte, length)
+	if _, err := r.ReadAt(idx, int64(off)); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(idx, castagnoli) != binary.LittleEndian.Uint32(footer[16:]) {
+		return nil, ErrChecksum
+	}
+
+	t := &Table[K, V]{r: r, kc: kc, vc: vc}
+	for _, want := range []string{kc.Name(), vc.Name()} {
+		name, n, err := codec.DecodeString(idx)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		if name != want {
+			return nil, fmt.Errorf("lsm: table was written with codec %q, reading with %q", name, want)
+		}
+		idx = idx[n:]
+	}
+	count, n := binary.Uvarint(idx)
+	if n <= 0 {
+		return nil, ErrFormat
+	}
+	idx = idx[n:]
+	blocks, n := binary.Uvarint(idx)
+	if n <= 0 || blocks > uint64(len(idx)) {
+		return nil, ErrFormat
+	}
+	idx = idx[n:]
+	t.count = int(count)
+	t.index = make([]blockHandle[K], blocks)
+	for i := range t.index {
+		first, n, err := kc.Decode(idx)
+		if err != nil {
+			return nil, err
+		}
+		idx = idx[n:]
+		offset, n1 := binary.Uvarint(idx)
+		if n1 <= 0 {
+			return nil, ErrFormat
+		}
+		length, n2 := binary.Uvarint(idx[n1:])
+		if n2 <= 0 || offset+length+4 > off {
+			return nil, ErrFormat
+		}
+		idx = idx[n1+n2:]
+		t.index[i] = blockHandle[K]{first: first, offset: int64(offset), length: int(length)}
+	}
+	return t, nil
+}
+
+// the number of records in the table, tombstones included
+func (t *Table[K, V]) Len() int {
+	return t.count
+}
+
+// look up the record for key, which may be a tombstone
+func (t *Table[K, V]) Get(key K) (Record[K, V], bool, error) {
+	for r, err := range t.scan(t.find(key), &key, &key) {
+		return r, err == nil, err
+	}
+	return Record[K, V]{}, false, nil
+}
+
+// iterate over the records in [lo..hi] in key order, tombstones included.
+// iteration stops after the first error
+func (t *Table[K, V]) Range(lo K, hi K) func(func(Record[K, V], error) bool) {
+	return t.scan(t.find(lo), &lo, &hi)
+}
+
+// iterate over all records in key order, tombstones included
+func (t *Table[K, V]) Iterator() func(func(Record[K, V], error) bool) {
+	return t.scan(0, nil, nil)
+}
+
+// find the block that would hold key, the last one whose first key is <= key
+func (t *Table[K, V]) find(key K) int {
+	i := sort.Search(len(t.index), func(i int) bool { return key < t.index[i].first })
+	return max(i-1, 0)
+}
+
+// iterate from block b over the records within the optional bounds
+func (t *Table[K, V]) scan(b int, lo *K, hi *K) func(func(Record[K, V], error) bool) {
+	return func(yield func(Record[K, V], error) bool) {
+		for ; b < len(t.index); b++ {
+			if hi != nil && *hi < t.index[b].first {
+				return
+			}
+			block, err := t.readBlock(b)
+			if err != nil {
+				yield(Record[K, V]{}, err)
+				return
+			}
+			for len(block) > 0 {
+				r, n, err := t.decode(block)
+				if err != nil {
+					yield(Record[K, V]{}, err)
+					return
+				}
+				block = block[n:]
+				if lo != nil && r.Key < *lo {
+					continue
+				}
+				if hi != nil && *hi < r.Key {
+					return
+				}
+				if !yield(r, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// read block b and verify its checksum
+func (t *Table[K, V]) readBlock(b int) ([]byte, error) {
+	h := t.index[b]
+	buf := make([]byte, h.length+4)
+	if _, err := t.r.ReadAt(buf, h.offset); err != nil {
+		return nil, err
+	}
+	block := buf[:h.length]
+	if crc32.Checksum(block, castagnoli) != binary.LittleEndian.Uint32(buf[h.length:]) {
+		return nil, ErrChecksum
+	}
+	return block, nil
+}
+
+// decode the record at the front of a block
+func (t *Table[K, V]) decode(buf []byte) (Record[K, V], int, error) {
+	var r Record[K, V]
+	if len(buf) == 0 || buf[0] > 1 {
+		return r, 0, ErrFormat
+	}
+	r.Deleted = buf[0] == 1
+	k, n, err := t.kc.Decode(buf[1:])
+	if err != nil {
+		return r, 0, err
+	}
+	r.Key = k
+	used := 1 + n
+	if !r.Deleted {
+		v, n, err := t.vc.Decode(buf[used:])
+		if err != nil {
+			return r, 0, err
+		}
+		r.Val = v
+		used += n
+	}
+	return r, used, nil
+}
@@ -0,0 +1,177 @@
+package lsm
+
+import (
+	"container/heap"
+	"io"
+	"iter"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// View is a read-only, merged view of a memtable and SSTables. the newest
+// record for a key wins and a tombstone hides the key from the view
+type View[K constraints.Ordered, V any] struct {
+	mem    *Memtable[K, V]
+	tables []*Table[K, V] // newest first
+}
+
+// create a view over mem, which may be nil, and tables ordered newest first
+func NewView[K constraints.Ordered, V any](mem *Memtable[K, V], tables ...*Table[K, V]) *View[K, V] {
+	return &View[K, V]{mem: mem, tables: tables}
+}
+
+// get the newest value of key, false if it is absent or deleted
+func (v *View[K, V]) Get(key K) (V, bool, error) {
+	var zero V
+	if v.mem != nil {
+		if r, ok := v.mem.lookup(key); ok {
+			return r.Val, !r.Deleted, nil
+		}
+	}
+	for _, t := range v.tables {
+		r, ok, err := t.Get(key)
+		if err != nil {
+			return zero, false, err
+		}
+		if ok {
+			return r.Val, !r.Deleted, nil
+		}
+	}
+	return zero, false, nil
+}
+
+// iterate over the live keys in [lo..hi] in order. iteration stops after the first error
+func (v *View[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V], error) bool) {
+	sources := make([]func(func(Record[K, V], error) bool), 0, len(v.tables)+1)
+	if v.mem != nil {
+		sources = append(sources, v.mem.Range(lo, hi))
+	}
+	for _, t := range v.tables {
+		sources = append(sources, t.Range(lo, hi))
+	}
+	return live(merge(sources))
+}
+
+// iterate over all live keys in order. iteration stops after the first error
+func (v *View[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V], error) bool) {
+	sources := make([]func(func(Record[K, V], error) bool), 0, len(v.tables)+1)
+	if v.mem != nil {
+		sources = append(sources, v.mem.Iterator())
+	}
+	for _, t := range v.tables {
+		sources = append(sources, t.Iterator())
+	}
+	return live(merge(sources))
+}
+
+// Compact merges tables, ordered newest first, into a single table written
+// to w using the codecs of the first table. tombstones must be kept unless
+// the output replaces every table that might hold an older value of the key
+func Compact[K constraints.Ordered, V any](w io.Writer, dropTombstones bool, tables ...*Table[K, V]) error {
+	if len(tables) == 0 {
+		return writeTable(w, merge[K, V](nil), codec.Default[K](), codec.Default[V]())
+	}
+	sources := make([]func(func(Record[K, V], error) bool), len(tables))
+	for i, t := range tables {
+		sources[i] = t.Iterator()
+	}
+	records := merge(sources)
+	if dropTombstones {
+		merged := records
+		records = func(yield func(Record[K, V], error) bool) {
+			for r, err := range merged {
+				if (err != nil || !r.Deleted) && !yield(r, err) {
+					return
+				}
+			}
+		}
+	}
+	return writeTable(w, records, tables[0].kc, tables[0].vc)
+}
+
+// drop tombstones and convert records to key value pairs
+func live[K constraints.Ordered, V any](records func(func(Record[K, V], error) bool)) func(func(rbt.KeyValuePair[K, V], error) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V], error) bool) {
+		for r, err := range records {
+			if err != nil {
+				yield(rbt.KeyValuePair[K, V]{}, err)
+				return
+			}
+			if !r.Deleted && !yield(rbt.KeyValuePair[K, V]{Key: r.Key, Val: r.Val}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// cursor is the current record of one merge source
+type cursor[K constraints.Ordered, V any] struct {
+	rec  Record[K, V]
+	age  int // position of the source, lower is newer
+	next func() (Record[K, V], error, bool)
+}
+
+// cursorHeap orders cursors by key, then by age so the newest record comes first
+type cursorHeap[K constraints.Ordered, V any] []*cursor[K, V]
+
+func (h cursorHeap[K, V]) Len() int { return len(h) }
+func (h cursorHeap[K, V]) Less(i, j int) bool {
+	if h[i].rec.Key != h[j].rec.Key {
+		return h[i].rec.Key < h[j].rec.Key
+	}
+	return h[i].age < h[j].age
+}
+func (h cursorHeap[K, V]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap[K, V]) Push(x any)   { *h = append(*h, x.(*cursor[K, V])) }
+func (h *cursorHeap[K, V]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// merge sorted sources, ordered newest first, into one sorted sequence that
+// holds only the newest record of each key. tombstones are passed through
+func merge[K constraints.Ordered, V any](sources []func(func(Record[K, V], error) bool)) func(func(Record[K, V], error) bool) {
+	return func(yield func(Record[K, V], error) bool) {
+		h := make(cursorHeap[K, V], 0, len(sources))
+		for age, src := range sources {
+			next, stop := iter.Pull2(iter.Seq2[Record[K, V], error](src))
+			defer stop()
+			r, err, ok := next()
+			if err != nil {
+				yield(r, err)
+				return
+			}
+			if ok {
+				h = append(h, &cursor[K, V]{rec: r, age: age, next: next})
+			}
+		}
+		heap.Init(&h)
+
+		for h.Len() > 0 {
+			newest := h[0].rec
+			// advance every cursor positioned on this key, the newest is first
+			for h.Len() > 0 && h[0].rec.Key == newest.Key {
+				c := h[0]
+				r, err, ok := c.next()
+				if err != nil {
+					yield(r, err)
+					return
+				}
+				if ok {
+					c.rec = r
+					heap.Fix(&h, 0)
+				} else {
+					heap.Pop(&h)
+				}
+			}
+			if !yield(newest, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package mvcc
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
+
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/lockfree"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// ErrVersionCollected is returned for reads at a version that GC has discarded
+var ErrVersionCollected = errors.New("mvcc: version has been garbage collected")
+
+// ErrVersionNotCommitted is returned for reads at a version newer than the last write
+var ErrVersionNotCommitted = errors.New("mvcc: version has not been committed")
+
+// MvccRBT is an ordered map that keeps old values so the tree can be read
+// as of any recent version while writes continue.
+//
+// Every Put or Delete creates a new version. Each key maps to a chain of
+// the values it has had, stamped with the version that wrote them, stored
+// in a lockfree tree so readers never block. Snapshot pins a version until
+// it is closed; GC discards values that no open snapshot can see.
+type MvccRBT[K constraints.Ordered, V any] struct {
+	tree    *lockfree.LockFreeRBT[K, []entry[V]]
+	mu      sync.Mutex    // serializes writers and GC
+	version atomic.Uint64 // last committed version
+	horizon atomic.Uint64 // oldest version that can still be read
+
+	readersMu sync.Mutex
+	readers   *gm.GeminiRBT[uint64, int] // pinned version -> open snapshots
+}
+
+// entry is one value of a key. chains are never modified once stored,
+// writers replace them
+type entry[V any] struct {
+	version uint64
+	val     V
+	deleted bool
+}
+
+// create a new tree at version 0
+func NewRBT[K constraints.Ordered, V any]() *MvccRBT[K, V] {
+	return &MvccRBT[K, V]{
+		tree:    lockfree.NewRBT[K, []entry[V]](),
+		readers: gm.NewRBT[uint64, int](),
+	}
+}
+
+// get the last committed version
+func (t *MvccRBT[K, V]) Version() uint64 {
+	return t.version.Load()
+}
+
+// insert a key-value pair as a new version
+func (t *MvccRBT[K, V]) Put(key K, val V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.write(key, entry[V]{val: val})
+}
+
+// remove a key as a new version
+func (t *MvccRBT[K, V]) Delete(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	chain, _ := t.tree.Get(key)
+	if len(chain) == 0 || chain[len(chain)-1].deleted {
+		return
+	}
+	t.write(key, entry[V]{deleted: true})
+}
+
+// append e to the chain of key. the new version is published after the
+// chain so a reader never sees an entry newer than the version it read
+func (t *MvccRBT[K, V]) write(key K, e entry[V]) {
+	e.version = t.version.Load() + 1
+	chain, _ := t.tree.Get(key)
+	t.tree.Put(key, append(chain[:len(chain):len(chain)], e))
+	t.version.Store(e.version)
+}
+
+// visible returns the value of a chain as of version
+func visible[V any](chain []entry[V], version uint64) (V, bool) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].version <= version {
+			if chain[i].deleted {
+				break
+			}
+			return chain[i].val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// get the latest value of a key
+func (t *MvccRBT[K, V]) Get(key K) (V, bool) {
+	chain, _ := t.tree.Get(key)
+	return visible(chain, t.version.Load())
+}
+
+// get the value of a key as of version
+func (t *MvccRBT[K, V]) GetAt(key K, version uint64) (V, bool, error) {
+	if version > t.version.Load() {
+		var zero V
+		return zero, false, ErrVersionNotCommitted
+	}
+	chain, _ := t.tree.Get(key)
+	// GC raises the horizon before it prunes, so checking after the
+	// read catches a chain that was pruned past version
+	if version < t.horizon.Load() {
+		var zero V
+		return zero, false, ErrVersionCollected
+	}
+	v, ok := visible(chain, version)
+	return v, ok, nil
+}
+
+// iterate over the key-value pairs as of version in order. the version is
+// not pinned, use a Snapshot to keep GC from discarding it mid-range
+func (t *MvccRBT[K, V]) IteratorAt(version uint64) (func(func(rbt.KeyValuePair[K, V]) bool), error) {
+	if version > t.version.Load() {
+		return nil, ErrVersionNotCommitted
+	}
+	if version < t.horizon.Load() {
+		return nil, ErrVersionCollected
+	}
+	return t.iterator(version), nil
+}
+
+func (t *MvccRBT[K, V]) iterator(version uint64) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for r := range t.tree.Iterator() {
+			if v, ok := visible(r.Val, version); ok {
+				if !yield(rbt.KeyValuePair[K, V]{Key: r.Key, Val: v}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// iterate over the latest key-value pairs in order
+func (t *MvccRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return t.iterator(t.version.Load())
+}
+
+// get all the latest key-value pairs in order
+func (t *MvccRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0)
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+// check if the latest version has no keys
+func (t *MvccRBT[K, V]) IsEmpty() bool {
+	for range t.Iterator() {
+		return false
+	}
+	return true
+}
+
+// ************ snapshots ************
+
+// Snapshot is a read view pinned at one version until Close
+type Snapshot[K constraints.Ordered, V any] struct {
+	t       *MvccRBT[K, V]
+	version uint64
+	closed  bool
+}
+
+// pin the current version for reading
+func (t *MvccRBT[K, V]) Snapshot() *Snapshot[K, V] {
+	t.readersMu.Lock()
+	defer t.readersMu.Unlock()
+	v := t.version.Load()
+	n, _ := t.readers.Get(v)
+	t.readers.Put(v, n+1)
+	return &Snapshot[K, V]{t: t, version: v}
+}
+
+// get the pinned version
+func (s *Snapshot[K, V]) Version() uint64 {
+	return s.version
+}
+
+// get the value of a key as of the pinned version
+func (s *Snapshot[K, V]) Get(key K) (V, bool) {
+	chain, _ := s.t.tree.Get(key)
+	return visible(chain, s.version)
+}
+
+// iterate over the key-value pairs as of the pinned version in order
+func (s *Snapshot[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return s.t.iterator(s.version)
+}
+
+// release the pinned version so GC can discard it
+func (s *Snapshot[K, V]) Close() {
+	t := s.t
+	t.readersMu.Lock()
+	defer t.readersMu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	n, _ := t.readers.Get(s.version)
+	if n <= 1 {
+		t.readers.Delete(s.version)
+	} else {
+		t.readers.Put(s.version, n-1)
+	}
+}
+
+// ************ garbage collection ************
+
+// discard every value that is not visible at or after the oldest open
+// snapshot, or the current version when there are none, and return the
+// number of entries removed. keys whose only remaining entry is a delete
+// are removed from the tree
+func (t *MvccRBT[K, V]) GC() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.readersMu.Lock()
+	horizon, ok := t.readers.Min()
+	if !ok {
+		horizon = t.version.Load()
+	}
+	t.readersMu.Unlock()
+	t.horizon.Store(horizon)
+
+	removed := 0
+	for r := range t.tree.Iterator() {
+		keep := prune(r.Val, horizon)
+		if len(keep) == len(r.Val) {
+			continue
+		}
+		removed += len(r.Val) - len(keep)
+		if len(keep) == 0 {
+			t.tree.Delete(r.Key)
+		} else {
+			t.tree.Put(r.Key, keep)
+		}
+	}
+	return removed
+}
+
+// prune returns the part of a chain that versions at or after horizon can see.
+// that is the newest entry at or before horizon and everything after it,
+// less a leading delete, which reads the same as no entry at all
+func prune[V any](chain []entry[V], horizon uint64) []entry[V] {
+	i := len(chain) - 1
+	for i >= 0 && chain[i].version > horizon {
+		i--
+	}
+	if i < 0 {
+		return chain
+	}
+	keep := chain[i:]
+	if keep[0].deleted {
+		keep = keep[1:]
+	}
+	return keep
+}
@@ -0,0 +1,165 @@
+package mvcc
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func keys(it func(func(rbt.KeyValuePair[int, string]) bool)) []int {
+	k := make([]int, 0)
+	for r := range it {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+func TestEmptyRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+	if rbt.Version() != 0 {
+		t.Errorf("Version() = %v; want 0", rbt.Version())
+	}
+}
+
+// test reads as of each version of a short history
+func TestGetAt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "a") // v1
+	rbt.Put(2, "b") // v2
+	rbt.Put(1, "c") // v3
+	rbt.Delete(2)   // v4
+	rbt.Delete(2)   // no-op
+	rbt.Put(2, "d") // v5
+
+	if rbt.Version() != 5 {
+		t.Fatalf("Version() = %v; want 5", rbt.Version())
+	}
+
+	tests := []struct {
+		key     int
+		version uint64
+		want    string
+		ok      bool
+	}{
+		{1, 0, "", false},
+		{1, 1, "a", true},
+		{1, 2, "a", true},
+		{1, 3, "c", true},
+		{1, 5, "c", true},
+		{2, 1, "", false},
+		{2, 3, "b", true},
+		{2, 4, "", false},
+		{2, 5, "d", true},
+	}
+	for _, tc := range tests {
+		v, ok, err := rbt.GetAt(tc.key, tc.version)
+		if err != nil || v != tc.want || ok != tc.ok {
+			t.Errorf("GetAt(%v, %v) = %v, %v, %v; want %v, %v", tc.key, tc.version, v, ok, err, tc.want, tc.ok)
+		}
+	}
+	if _, _, err := rbt.GetAt(1, 6); err != ErrVersionNotCommitted {
+		t.Errorf("GetAt(1, 6) error = %v; want ErrVersionNotCommitted", err)
+	}
+
+	it, err := rbt.IteratorAt(4)
+	if err != nil {
+		t.Fatalf("IteratorAt(4) error = %v", err)
+	}
+	if got, want := keys(it), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("IteratorAt(4) = %v; want %v", got, want)
+	}
+	if got, want := keys(rbt.Iterator()), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %v; want %v", got, want)
+	}
+}
+
+// test that GC keeps what open snapshots can see and drops the rest
+func TestGC(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "a")
+	rbt.Put(2, "b")
+	snap := rbt.Snapshot() // v2
+	rbt.Put(1, "c")
+	rbt.Delete(2)
+	rbt.Put(3, "e")
+
+	if n := rbt.GC(); n != 0 {
+		t.Errorf("GC() = %v with snapshot at v2; want 0", n)
+	}
+	if v, ok := snap.Get(2); !ok || v != "b" {
+		t.Errorf("snapshot Get(2) = %v, %v; want b, true", v, ok)
+	}
+	if got, want := keys(snap.Iterator()), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("snapshot Iterator() = %v; want %v", got, want)
+	}
+
+	snap.Close()
+	snap.Close()
+	// 1: a is shadowed by c, 2: b and its delete go
+	if n := rbt.GC(); n != 3 {
+		t.Errorf("GC() = %v; want 3", n)
+	}
+	if _, _, err := rbt.GetAt(1, 2); err != ErrVersionCollected {
+		t.Errorf("GetAt(1, 2) error = %v; want ErrVersionCollected", err)
+	}
+	if _, err := rbt.IteratorAt(2); err != ErrVersionCollected {
+		t.Errorf("IteratorAt(2) error = %v; want ErrVersionCollected", err)
+	}
+	if got, want := keys(rbt.Iterator()), []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %v; want %v", got, want)
+	}
+	if rbt.tree.Size() != 2 {
+		t.Errorf("%v keys left in tree; want 2", rbt.tree.Size())
+	}
+}
+
+// a snapshot taken while writes keep flowing must keep returning the same view
+func TestSnapshotStable(t *testing.T) {
+	tree := NewRBT[int, string]()
+	for i := 0; i < 100; i++ {
+		tree.Put(i, "x")
+	}
+
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; !done.Load(); i++ {
+			tree.Put(i%200, "y")
+			tree.Delete((i + 50) % 200)
+			if i%100 == 0 {
+				tree.GC()
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		snap := tree.Snapshot()
+		a := make([]rbt.KeyValuePair[int, string], 0)
+		for r := range snap.Iterator() {
+			a = append(a, r)
+		}
+		b := make([]rbt.KeyValuePair[int, string], 0, len(a))
+		for r := range snap.Iterator() {
+			b = append(b, r)
+		}
+		if !slices.Equal(a, b) {
+			t.Errorf("snapshot at v%v changed while open", snap.Version())
+		}
+		for _, r := range a {
+			if v, ok, err := tree.GetAt(r.Key, snap.Version()); err != nil || !ok || v != r.Val {
+				t.Errorf("GetAt(%v, %v) = %v, %v, %v; want %v", r.Key, snap.Version(), v, ok, err, r.Val)
+			}
+		}
+		snap.Close()
+	}
+	done.Store(true)
+	wg.Wait()
+}
@@ -0,0 +1,110 @@
+package rbt
+
+import "golang.org/x/exp/constraints"
+
+// Neighbors looks up the entries next to a key, which is all a tree has to
+// provide for Nearest and WithinDistance. Floor finds the largest key <= k,
+// Lower the largest key < k and Higher the smallest key > k.
+type Neighbors[K constraints.Ordered, V any] struct {
+	Floor  func(k K) (KeyValuePair[K, V], bool)
+	Lower  func(k K) (KeyValuePair[K, V], bool)
+	Higher func(k K) (KeyValuePair[K, V], bool)
+}
+
+// Nearest returns an iterator over the k entries whose keys are closest to key,
+// in increasing distance order. The search starts at the floor and ceiling of
+// key and expands outward one neighbor at a time. When two keys are the same
+// distance from key, the smaller key is yielded first.
+func Nearest[K Number, V any](nb Neighbors[K, V], key K, k int) func(func(KeyValuePair[K, V]) bool) {
+	return func(yield func(KeyValuePair[K, V]) bool) {
+		if k <= 0 {
+			return
+		}
+		n := 0
+		nearest(nb, key, func(p KeyValuePair[K, V]) bool {
+			n++
+			return yield(p) && n < k
+		})
+	}
+}
+
+// WithinDistance returns an iterator over the entries whose keys are no more
+// than d away from key, in the same order and with the same tie rule as Nearest.
+func WithinDistance[K Number, V any](nb Neighbors[K, V], key K, d K) func(func(KeyValuePair[K, V]) bool) {
+	return func(yield func(KeyValuePair[K, V]) bool) {
+		if d < 0 {
+			return
+		}
+		nearest(nb, key, func(p KeyValuePair[K, V]) bool {
+			if compareDistance(key, p.Key, 0, d) > 0 {
+				return false
+			}
+			return yield(p)
+		})
+	}
+}
+
+// nearest visits every entry in increasing distance from key until visit returns false.
+// lo walks down from the floor of key, hi walks up from the smallest key above it.
+func nearest[K Number, V any](nb Neighbors[K, V], key K, visit func(KeyValuePair[K, V]) bool) {
+	lo, okLo := nb.Floor(key)
+	hi, okHi := nb.Higher(key)
+	for okLo || okHi {
+		if !okHi || (okLo && compareDistance(key, lo.Key, key, hi.Key) <= 0) {
+			if !visit(lo) {
+				return
+			}
+			lo, okLo = nb.Lower(lo.Key)
+		} else {
+			if !visit(hi) {
+				return
+			}
+			hi, okHi = nb.Higher(hi.Key)
+		}
+	}
+}
+
+// compareDistance compares |a-b| with |c-d| without computing either in K,
+// where the difference of two keys can overflow: signed keys of opposite
+// sign can be further apart than the largest K, and unsigned keys wrap when
+// subtracted the wrong way round
+func compareDistance[K Number](a, b, c, d K) int {
+	var half K = 1
+	if half/2 != 0 {
+		// a float, where a difference too large at worst rounds to +Inf
+		return compare(absDiff(float64(a), float64(b)), absDiff(float64(c), float64(d)))
+	}
+	var minusOne K
+	minusOne--
+	if minusOne > 0 {
+		// unsigned, the larger key minus the smaller always fits
+		return compare(absDiff(a, b), absDiff(c, d))
+	}
+	return compare(signedDiff(int64(a), int64(b)), signedDiff(int64(c), int64(d)))
+}
+
+// the larger of a and b minus the smaller
+func absDiff[T Number](a, b T) T {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
+
+// |a-b| for signed keys, which is below 2^64 so it is exact in uint64
+func signedDiff(a, b int64) uint64 {
+	if a < b {
+		a, b = b, a
+	}
+	return uint64(a) - uint64(b)
+}
+
+func compare[T constraints.Ordered](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package rbt
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// neighbors of a sorted slice of keys, standing in for a tree
+func sliceNeighbors[K Number](keys []K) Neighbors[K, int] {
+	at := func(i int) (KeyValuePair[K, int], bool) {
+		if i < 0 || i >= len(keys) {
+			return KeyValuePair[K, int]{}, false
+		}
+		return KeyValuePair[K, int]{Key: keys[i], Val: i}, true
+	}
+	return Neighbors[K, int]{
+		Floor: func(k K) (KeyValuePair[K, int], bool) {
+			return at(sort.Search(len(keys), func(i int) bool { return keys[i] > k }) - 1)
+		},
+		Lower: func(k K) (KeyValuePair[K, int], bool) {
+			return at(sort.Search(len(keys), func(i int) bool { return keys[i] >= k }) - 1)
+		},
+		Higher: func(k K) (KeyValuePair[K, int], bool) {
+			return at(sort.Search(len(keys), func(i int) bool { return keys[i] > k }))
+		},
+	}
+}
+
+func collect[K Number](seq func(func(KeyValuePair[K, int]) bool)) []K {
+	var keys []K
+	for p := range seq {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
+
+func TestNearest(t *testing.T) {
+	nb := sliceNeighbors([]int{1, 4, 6, 10, 14, 20})
+	if got, want := collect(Nearest(nb, 10, 5)), []int{10, 6, 14, 4, 1}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(10, 5) = %v; want %v", got, want)
+	}
+	// 4 and 6 are both 1 away from 5, the smaller comes first
+	if got, want := collect(Nearest(nb, 5, 3)), []int{4, 6, 1}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(5, 3) = %v; want %v", got, want)
+	}
+	if got := collect(Nearest(nb, 5, 0)); len(got) != 0 {
+		t.Errorf("Nearest(5, 0) = %v; want []", got)
+	}
+}
+
+// keys at the ends of a signed type are further apart than the type can hold
+func TestNearestSignedLimits(t *testing.T) {
+	nb := sliceNeighbors([]int64{math.MinInt64, -1, 0, math.MaxInt64})
+	if got, want := collect(Nearest(nb, 0, 4)), []int64{0, -1, math.MaxInt64, math.MinInt64}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(0) = %v; want %v", got, want)
+	}
+	if got, want := collect(Nearest(nb, math.MaxInt64, 4)), []int64{math.MaxInt64, 0, -1, math.MinInt64}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(MaxInt64) = %v; want %v", got, want)
+	}
+	if got, want := collect(Nearest(nb, math.MinInt64, 4)), []int64{math.MinInt64, -1, 0, math.MaxInt64}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(MinInt64) = %v; want %v", got, want)
+	}
+	// -1 is 2^63 away from MaxInt64, already more than any int64 d
+	if got, want := collect(WithinDistance(nb, math.MaxInt64, math.MaxInt64)), []int64{math.MaxInt64, 0}; !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(MaxInt64, MaxInt64) = %v; want %v", got, want)
+	}
+
+	small := sliceNeighbors([]int8{math.MinInt8, 0, math.MaxInt8})
+	if got, want := collect(Nearest(small, math.MaxInt8, 3)), []int8{math.MaxInt8, 0, math.MinInt8}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(MaxInt8) = %v; want %v", got, want)
+	}
+	if got, want := collect(WithinDistance(small, math.MinInt8, 127)), []int8{math.MinInt8}; !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(MinInt8, 127) = %v; want %v", got, want)
+	}
+}
+
+// unsigned probes outside the keys must not wrap
+func TestNearestUnsigned(t *testing.T) {
+	nb := sliceNeighbors([]uint8{10, 200, 255})
+	if got, want := collect(Nearest(nb, 0, 3)), []uint8{10, 200, 255}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(0) = %v; want %v", got, want)
+	}
+	if got, want := collect(Nearest(nb, 105, 3)), []uint8{10, 200, 255}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(105) = %v; want %v", got, want)
+	}
+	if got, want := collect(WithinDistance(nb, 255, 55)), []uint8{255, 200}; !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(255, 55) = %v; want %v", got, want)
+	}
+	big := sliceNeighbors([]uint64{0, math.MaxUint64})
+	if got, want := collect(Nearest(big, 1, 2)), []uint64{0, math.MaxUint64}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(1) = %v; want %v", got, want)
+	}
+}
+
+func TestWithinDistanceFloat(t *testing.T) {
+	nb := sliceNeighbors([]float64{-math.MaxFloat64, 0.5, 1.0, 1.25, 2.0, 3.5, math.MaxFloat64})
+	if got, want := collect(WithinDistance(nb, 1.5, 0.5)), []float64{1.25, 1.0, 2.0}; !slices.Equal(got, want) {
+		t.Errorf("WithinDistance(1.5, 0.5) = %v; want %v", got, want)
+	}
+	if got := collect(WithinDistance(nb, 1.5, -1)); len(got) != 0 {
+		t.Errorf("WithinDistance(1.5, -1) = %v; want []", got)
+	}
+	if got, want := collect(Nearest(nb, math.MaxFloat64, 2)), []float64{math.MaxFloat64, 3.5}; !slices.Equal(got, want) {
+		t.Errorf("Nearest(MaxFloat64) = %v; want %v", got, want)
+	}
+}
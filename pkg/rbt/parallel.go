@@ -0,0 +1,92 @@
+package rbt
+
+import (
+	"runtime"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Ranks visits, in key order, the entries of a tree whose rank is in [lo, hi).
+// A tree that keeps subtree sizes finds the range without scanning the entries before it,
+// which is what lets the parallel helpers split the tree into equal parts.
+type Ranks[K constraints.Ordered, V any] func(lo int, hi int, visit func(K, V))
+
+// ParallelForEach calls fn for every one of the n entries ranks reaches, spreading the work
+// over workers goroutines. Each worker gets a contiguous range of about n/workers ranks.
+// fn is called concurrently and in no particular order across workers.
+// workers <= 0 uses GOMAXPROCS.
+func ParallelForEach[K constraints.Ordered, V any](ranks Ranks[K, V], n int, workers int, fn func(K, V)) {
+	parallel(n, workers, func(_ int, lo int, hi int) {
+		ranks(lo, hi, fn)
+	})
+}
+
+// ParallelReduce folds the n entries ranks reaches into a result using workers goroutines.
+// Each worker folds its range of entries in key order into a fresh accumulator from init,
+// then the partial results are combined left to right in key order. init must return an
+// identity of combine, and a new one on every call when R is a slice, map or pointer,
+// since every worker mutates its own; combine only has to be associative.
+// An empty tree gives init().
+func ParallelReduce[K constraints.Ordered, V any, R any](ranks Ranks[K, V], n int, workers int, init func() R, accumulate func(R, K, V) R, combine func(R, R) R) R {
+	parts := make([]R, max(parallelism(n, workers), 1))
+	for i := range parts {
+		parts[i] = init()
+	}
+	parallel(n, workers, func(i int, lo int, hi int) {
+		acc := parts[i]
+		ranks(lo, hi, func(k K, v V) {
+			acc = accumulate(acc, k, v)
+		})
+		parts[i] = acc
+	})
+	result := parts[0]
+	for _, p := range parts[1:] {
+		result = combine(result, p)
+	}
+	return result
+}
+
+// ParallelMap is the ordered mode: it calls fn for each of the n entries ranks reaches,
+// using workers goroutines, and returns the results in key order. Each worker writes
+// its range of ranks straight into its part of the result, so no combine step is needed.
+func ParallelMap[K constraints.Ordered, V any, R any](ranks Ranks[K, V], n int, workers int, fn func(K, V) R) []R {
+	out := make([]R, n)
+	parallel(n, workers, func(_ int, lo int, hi int) {
+		i := lo
+		ranks(lo, hi, func(k K, v V) {
+			out[i] = fn(k, v)
+			i++
+		})
+	})
+	return out
+}
+
+// the number of workers used for n entries
+func parallelism(n int, workers int) int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return min(workers, n)
+}
+
+// split the ranks [0, n) into one contiguous part per worker and call part
+// for each on its own goroutine, or inline when there is a single worker
+func parallel(n int, workers int, part func(i int, lo int, hi int)) {
+	workers = parallelism(n, workers)
+	if workers <= 1 {
+		if n > 0 {
+			part(0, 0, n)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			part(i, i*n/workers, (i+1)*n/workers)
+		}(i)
+	}
+	wg.Wait()
+}
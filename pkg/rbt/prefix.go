@@ -0,0 +1,45 @@
+package rbt
+
+import "strings"
+
+// PrefixScan returns an iterator over the entries whose keys start with prefix, in key order.
+// ascend iterates over a tree in key order from the smallest key >= from. Matching keys
+// form a contiguous run starting at prefix, so a tree whose ascend prunes the subtrees
+// below from scans in O(log n + k).
+func PrefixScan[K ~string, V any](ascend func(from K) func(func(KeyValuePair[K, V]) bool), prefix K) func(func(KeyValuePair[K, V]) bool) {
+	return func(yield func(KeyValuePair[K, V]) bool) {
+		for p := range ascend(prefix) {
+			if !strings.HasPrefix(string(p.Key), string(prefix)) || !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// LongestPrefixOf returns the longest key that is a prefix of s, given floor, which finds
+// the largest key <= k in a tree. Any such key sorts at or below s, so the search starts
+// at the floor of s and shortens s to its common prefix with the floor until the floor matches.
+func LongestPrefixOf[K ~string, V any](floor func(k K) (KeyValuePair[K, V], bool), s K) (K, bool) {
+	for {
+		p, ok := floor(s)
+		if !ok {
+			var zero K
+			return zero, false
+		}
+		if strings.HasPrefix(string(s), string(p.Key)) {
+			return p.Key, true
+		}
+		s = s[:commonPrefix(s, p.Key)]
+	}
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b
+func commonPrefix[K ~string](a K, b K) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -14,3 +14,48 @@ type RBT[K constraints.Ordered, V any] interface {
 	GetAll() []KeyValuePair[K, V]
 	Iterator() func(yield func(KeyValuePair[K, V]) bool)
 }
+
+// Number is the set of key types that support distance queries
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+// OrderedRBT is an RBT that tracks its size and can remove its smallest and largest keys
+type OrderedRBT[K constraints.Ordered, V any] interface {
+	RBT[K, V]
+	Size() int
+	Min() (K, bool)
+	Max() (K, bool)
+	DeleteMin()
+	DeleteMax()
+}
+
+// EventKind is the kind of change a mutation made to a key
+type EventKind int
+
+const (
+	EventPut    EventKind = iota // a new key was inserted
+	EventUpdate                  // an existing key got a new value
+	EventDelete                  // a key was removed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventPut:
+		return "put"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event describes one change to a tree. Old is set for updates and deletes,
+// New for puts and updates
+type Event[K constraints.Ordered, V any] struct {
+	Kind EventKind
+	Key  K
+	Old  V
+	New  V
+}
@@ -0,0 +1,250 @@
+package sharded
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"math"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// ShardedRBT spreads keys over several trees by hash so that writers to
+// different shards do not contend for the same lock.
+//
+// Point operations lock one shard. Ordered operations read lock every
+// shard for as long as the iteration runs and k-way merge the shards' own
+// iterators, so they see a snapshot across shards without copying it.
+// Writers wait until the iteration ends, so the loop body of Iterator or
+// Range must not call the ShardedRBT at all: a Put deadlocks at once, and a
+// Get, Size or GetAll deadlocks as soon as a writer is queued on a shard,
+// because sync.RWMutex blocks new readers once a writer is waiting.
+type ShardedRBT[K constraints.Ordered, V any] struct {
+	shards []shard[K, V]
+	seed   maphash.Seed
+}
+
+// Tree is what a shard holds: an ordered tree that can iterate over the
+// keys in [lo..hi] without visiting the rest of it
+type Tree[K constraints.Ordered, V any] interface {
+	rbt.OrderedRBT[K, V]
+	Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool)
+}
+
+type shard[K constraints.Ordered, V any] struct {
+	mu   sync.RWMutex
+	tree Tree[K, V]
+}
+
+// create a tree with n shards, each built by newTree
+func NewRBT[K constraints.Ordered, V any](n int, newTree func() Tree[K, V]) *ShardedRBT[K, V] {
+	if n < 1 {
+		n = 1
+	}
+	s := &ShardedRBT[K, V]{
+		shards: make([]shard[K, V], n),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range s.shards {
+		s.shards[i].tree = newTree()
+	}
+	return s
+}
+
+// get the shard that owns key
+func (s *ShardedRBT[K, V]) shardFor(key K) *shard[K, V] {
+	// -0 and +0 are the same key but have different bits, hash them as +0
+	var zero K
+	if key == zero {
+		key = zero
+	}
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	var b [8]byte
+	switch k := any(key).(type) {
+	case string:
+		h.WriteString(k)
+	case int:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case int8:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case int16:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case int32:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case int64:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case uint:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case uint8:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case uint16:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case uint32:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case uint64:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], k))
+	case uintptr:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+	case float32:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], math.Float64bits(float64(k))))
+	case float64:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], math.Float64bits(k)))
+	default:
+		// named key types
+		h.WriteString(fmt.Sprint(key))
+	}
+	return &s.shards[h.Sum64()%uint64(len(s.shards))]
+}
+
+// ************ point operations ************
+
+func (s *ShardedRBT[K, V]) Put(key K, val V) {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.tree.Put(key, val)
+}
+
+func (s *ShardedRBT[K, V]) Get(key K) (V, bool) {
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.tree.Get(key)
+}
+
+// get the total number of key-value pairs
+func (s *ShardedRBT[K, V]) Size() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		n += sh.tree.Size()
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+func (s *ShardedRBT[K, V]) IsEmpty() bool {
+	return s.Size() == 0
+}
+
+// ************ ordered operations ************
+
+// get the smallest key over all shards
+func (s *ShardedRBT[K, V]) Min() (K, bool) {
+	return s.extreme(func(t Tree[K, V]) (K, bool) { return t.Min() }, func(a, b K) bool { return a < b })
+}
+
+// get the largest key over all shards
+func (s *ShardedRBT[K, V]) Max() (K, bool) {
+	return s.extreme(func(t Tree[K, V]) (K, bool) { return t.Max() }, func(a, b K) bool { return a > b })
+}
+
+func (s *ShardedRBT[K, V]) extreme(get func(Tree[K, V]) (K, bool), better func(K, K) bool) (K, bool) {
+	var best K
+	found := false
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		k, ok := get(sh.tree)
+		sh.mu.RUnlock()
+		if ok && (!found || better(k, best)) {
+			best, found = k, true
+		}
+	}
+	return best, found
+}
+
+// get all key-value pairs in order
+func (s *ShardedRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, s.Size())
+	for r := range s.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+// iterate over all key-value pairs in order, the loop body must not call s
+func (s *ShardedRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return s.merged(func(t Tree[K, V]) func(func(rbt.KeyValuePair[K, V]) bool) {
+		return t.Iterator()
+	})
+}
+
+// iterate over the key-value pairs with keys in [lo..hi] in order, the loop
+// body must not call s
+func (s *ShardedRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return s.merged(func(t Tree[K, V]) func(func(rbt.KeyValuePair[K, V]) bool) {
+		return t.Range(lo, hi)
+	})
+}
+
+// read lock every shard and merge the iterators seq gets from them, holding
+// the locks until the iteration ends. the shards are locked in index order,
+// so two merges waiting on each other's shards cannot deadlock
+func (s *ShardedRBT[K, V]) merged(seq func(Tree[K, V]) func(func(rbt.KeyValuePair[K, V]) bool)) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		sources := make([]func(func(rbt.KeyValuePair[K, V]) bool), len(s.shards))
+		for i := range s.shards {
+			sh := &s.shards[i]
+			sh.mu.RLock()
+			defer sh.mu.RUnlock()
+			sources[i] = seq(sh.tree)
+		}
+		merge(sources, yield)
+	}
+}
+
+// ************ k-way merge ************
+
+// cursor is the current pair of one shard's iterator
+type cursor[K constraints.Ordered, V any] struct {
+	pair rbt.KeyValuePair[K, V]
+	next func() (rbt.KeyValuePair[K, V], bool)
+}
+
+// cursorHeap is a min-heap of cursors ordered by key
+type cursorHeap[K constraints.Ordered, V any] []*cursor[K, V]
+
+func (h cursorHeap[K, V]) Len() int           { return len(h) }
+func (h cursorHeap[K, V]) Less(i, j int) bool { return h[i].pair.Key < h[j].pair.Key }
+func (h cursorHeap[K, V]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap[K, V]) Push(x any)        { *h = append(*h, x.(*cursor[K, V])) }
+func (h *cursorHeap[K, V]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// yield the pairs of the sorted sources in key order, pulling one pair at a
+// time from each. shards partition the keys, so the sources never share a key
+func merge[K constraints.Ordered, V any](sources []func(func(rbt.KeyValuePair[K, V]) bool), yield func(rbt.KeyValuePair[K, V]) bool) {
+	h := make(cursorHeap[K, V], 0, len(sources))
+	for _, src := range sources {
+		next, stop := iter.Pull(iter.Seq[rbt.KeyValuePair[K, V]](src))
+		defer stop()
+		if p, ok := next(); ok {
+			h = append(h, &cursor[K, V]{pair: p, next: next})
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		c := h[0]
+		if !yield(c.pair) {
+			return
+		}
+		if p, ok := c.next(); ok {
+			c.pair = p
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package sharded
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+
+	"sqirvy.xyz/go-tree-iterator/avl"
+	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+	"sqirvy.xyz/go-tree-iterator/syncrbt"
+)
+
+func newCopilot() Tree[int, int] { return cp.NewRBT[int, int]() }
+func newGemini() Tree[int, int]  { return gm.NewRBT[int, int]() }
+func newChatGpt() Tree[int, int] { return ch.NewRBT[int, int]() }
+func newAvl() Tree[int, int]     { return avl.NewRBT[int, int]() }
+
+func TestEmptyRbt(t *testing.T) {
+	s := NewRBT(8, newGemini)
+	if !s.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", s.IsEmpty())
+	}
+	if k, ok := s.Min(); ok {
+		t.Errorf("Min() = %v; want false", k)
+	}
+	for r := range s.Iterator() {
+		t.Errorf("Iterator() yielded %v", r)
+	}
+}
+
+// test the merged ordered operations against a sorted slice
+func TestOrdered(t *testing.T) {
+	for _, newTree := range []func() Tree[int, int]{newCopilot, newGemini, newChatGpt, newAvl} {
+		s := NewRBT(7, newTree)
+		m := make(map[int]int)
+		for i := 0; i < 1000; i++ {
+			k := rand.Intn(5000)
+			s.Put(k, i)
+			m[k] = i
+		}
+		keys := make([]int, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+
+		if s.Size() != len(keys) {
+			t.Errorf("Size() = %v; want %v", s.Size(), len(keys))
+		}
+		for k, v := range m {
+			if x, ok := s.Get(k); !ok || x != v {
+				t.Errorf("Get(%v) = %v, %v; want %v", k, x, ok, v)
+			}
+		}
+		if k, _ := s.Min(); k != keys[0] {
+			t.Errorf("Min() = %v; want %v", k, keys[0])
+		}
+		if k, _ := s.Max(); k != keys[len(keys)-1] {
+			t.Errorf("Max() = %v; want %v", k, keys[len(keys)-1])
+		}
+
+		got := make([]int, 0)
+		for _, r := range s.GetAll() {
+			got = append(got, r.Key)
+		}
+		if !slices.Equal(got, keys) {
+			t.Errorf("GetAll() out of order")
+		}
+
+		got = got[:0]
+		for r := range s.Iterator() {
+			got = append(got, r.Key)
+		}
+		if !slices.Equal(got, keys) {
+			t.Errorf("Iterator() out of order")
+		}
+
+		lo, hi := 1000, 2000
+		want := make([]int, 0)
+		for _, k := range keys {
+			if k >= lo && k <= hi {
+				want = append(want, k)
+			}
+		}
+		got = got[:0]
+		for r := range s.Range(lo, hi) {
+			got = append(got, r.Key)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Range(%v, %v) = %v; want %v", lo, hi, got, want)
+		}
+	}
+}
+
+// test that -0 and +0 are one key, whichever shards their bits would pick
+func TestNegativeZero(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	for i := 0; i < 10; i++ {
+		s := NewRBT(64, func() Tree[float64, int] { return gm.NewRBT[float64, int]() })
+		s.Put(0, 1)
+		s.Put(negZero, 2)
+		if n := s.Size(); n != 1 {
+			t.Fatalf("Size() = %v after Put(0) and Put(-0); want 1", n)
+		}
+		if v, ok := s.Get(0); !ok || v != 2 {
+			t.Fatalf("Get(0) = %v, %v; want 2", v, ok)
+		}
+		if v, ok := s.Get(negZero); !ok || v != 2 {
+			t.Fatalf("Get(-0) = %v, %v; want 2", v, ok)
+		}
+	}
+}
+
+// test that leaving an iteration early releases the shard locks
+func TestIteratorBreak(t *testing.T) {
+	s := NewRBT(8, newGemini)
+	for i := 0; i < 100; i++ {
+		s.Put(i, i)
+	}
+	n := 0
+	for range s.Range(10, 90) {
+		if n++; n == 5 {
+			break
+		}
+	}
+	s.Put(1000, 1000)
+	if v, ok := s.Get(1000); !ok || v != 1000 {
+		t.Errorf("Get(1000) = %v, %v after a broken iteration", v, ok)
+	}
+}
+
+// test that keys spread over the shards
+func TestSpread(t *testing.T) {
+	s := NewRBT(4, newGemini)
+	for i := 0; i < 1000; i++ {
+		s.Put(i, i)
+	}
+	for i := range s.shards {
+		if n := s.shards[i].tree.Size(); n < 150 {
+			t.Errorf("shard %v has %v keys", i, n)
+		}
+	}
+}
+
+func TestConcurrentStress(t *testing.T) {
+	s := NewRBT(8, newGemini)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				k := rand.Intn(1000)
+				s.Put(k, k)
+				if v, ok := s.Get(rand.Intn(1000)); ok && v < 0 {
+					t.Errorf("Get() = %v", v)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			k := -1
+			for r := range s.Range(100, 900) {
+				if r.Key <= k {
+					t.Errorf("out of order %v after %v", r.Key, k)
+				}
+				k = r.Key
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// ************ throughput against a single-lock wrapper ************
+
+const benchKeys = 1 << 16
+
+func benchmarkMixed(b *testing.B, t rbt.RBT[int, int]) {
+	for i := 0; i < benchKeys; i++ {
+		t.Put(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := r.Intn(benchKeys)
+			if r.Intn(4) == 0 {
+				t.Put(k, k)
+			} else {
+				t.Get(k)
+			}
+		}
+	})
+}
+
+func BenchmarkSyncRBTMixed(b *testing.B) {
+	benchmarkMixed(b, syncrbt.NewRBT[int, int](gm.NewRBT[int, int]()))
+}
+
+func BenchmarkSharded16Mixed(b *testing.B) {
+	benchmarkMixed(b, NewRBT(16, newGemini))
+}
+
+func BenchmarkSyncRBTPut(b *testing.B) {
+	t := syncrbt.NewRBT[int, int](gm.NewRBT[int, int]())
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := r.Intn(benchKeys)
+			t.Put(k, k)
+		}
+	})
+}
+
+func BenchmarkSharded16Put(b *testing.B) {
+	t := NewRBT(16, newGemini)
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := r.Intn(benchKeys)
+			t.Put(k, k)
+		}
+	})
+}
+
+func BenchmarkSharded16Iterator(b *testing.B) {
+	t := NewRBT(16, newGemini)
+	for i := 0; i < benchKeys; i++ {
+		t.Put(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for range t.Iterator() {
+		}
+	}
+}
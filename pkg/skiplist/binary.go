@@ -0,0 +1,44 @@
+package skiplist
+
+import (
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, and through it gob encoding
+func (t *SkipListRBT[K, V]) MarshalBinary() ([]byte, error) {
+	return codec.MarshalBinary(&t.Codecs, t.Size(), t.Iterator())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. it replaces the
+// contents of the list, rebuilding it in linear time from the sorted stream.
+func (t *SkipListRBT[K, V]) UnmarshalBinary(data []byte) error {
+	return codec.UnmarshalBinary(&t.Codecs, data, t.load)
+}
+
+// replace the contents of the list with pairs sorted by key in linear time,
+// appending each node after the last node on each of its levels
+func (t *SkipListRBT[K, V]) load(pairs []rbt.KeyValuePair[K, V]) {
+	t.head = &Node[K, V]{next: make([]link[K, V], MaxLevel)}
+	t.level = 1
+	t.size = len(pairs)
+	var last [MaxLevel]*Node[K, V]
+	var rank [MaxLevel]int
+	for i := range last {
+		last[i] = t.head
+	}
+	for r, p := range pairs {
+		level := t.randomLevel()
+		t.level = max(t.level, level)
+		x := &Node[K, V]{key: p.Key, val: p.Val, next: make([]link[K, V], level)}
+		for i := 0; i < level; i++ {
+			last[i].next[i] = link[K, V]{node: x, span: r + 1 - rank[i]}
+			last[i] = x
+			rank[i] = r + 1
+		}
+	}
+	// the last link on each level spans the rest of the list
+	for i := 0; i < t.level; i++ {
+		last[i].next[i].span = t.size - rank[i]
+	}
+}
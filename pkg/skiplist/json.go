@@ -0,0 +1,14 @@
+package skiplist
+
+import "sqirvy.xyz/go-tree-iterator/codec"
+
+// MarshalJSON implements json.Marshaler. a list with string keys encodes as an
+// object with its keys in order, other lists as an array of [key, value] pairs.
+func (t *SkipListRBT[K, V]) MarshalJSON() ([]byte, error) {
+	return codec.MarshalJSON(t.Iterator())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it replaces the contents of the list
+func (t *SkipListRBT[K, V]) UnmarshalJSON(data []byte) error {
+	return codec.LoadJSON(data, t.load)
+}
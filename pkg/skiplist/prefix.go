@@ -0,0 +1,35 @@
+package skiplist
+
+import (
+	rbt "sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// PrefixScan returns an iterator over the entries whose keys start with prefix,
+// in key order. it searches once and then walks level 0, costing O(log n + k).
+// see rbt.PrefixScan
+func PrefixScan[K ~string, V any](t *SkipListRBT[K, V], prefix K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return rbt.PrefixScan(t.ascend, prefix)
+}
+
+// LongestPrefixOf returns the longest key in the list that is a prefix of s.
+// see rbt.LongestPrefixOf
+func LongestPrefixOf[K ~string, V any](t *SkipListRBT[K, V], s K) (K, bool) {
+	return rbt.LongestPrefixOf(func(k K) (rbt.KeyValuePair[K, V], bool) {
+		x := t.floor(k)
+		if x == nil {
+			return rbt.KeyValuePair[K, V]{}, false
+		}
+		return rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}, true
+	}, s)
+}
+
+// ascend iterates in order over the keys >= from, starting at the ceiling of from
+func (t *SkipListRBT[K, V]) ascend(from K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for x := t.ceiling(from); x != nil; x = x.next[0].node {
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+		}
+	}
+}
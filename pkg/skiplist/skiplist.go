@@ -0,0 +1,293 @@
+package skiplist
+
+import (
+	"math/rand"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// a skip list is a sorted linked list with express lanes: every node is on
+// level 0 and each level above holds about a quarter of the nodes below it,
+// so a search drops down the levels in O(log n) expected steps. updates only
+// relink the neighbours of one node, with no rebalancing, which is what
+// makes skip lists easier to make concurrent than trees.
+//
+// every link also records its span, the number of level 0 steps it skips,
+// which gives Select and Rank in O(log n) like an indexable skip list.
+// the spans follow redis's zskiplist: a link to nil spans the rest of the
+// list. https://github.com/redis/redis/blob/unstable/src/t_zset.c
+
+// MaxLevel bounds the height of a node, enough for 4^32 keys
+const MaxLevel = 32
+
+type link[K constraints.Ordered, V any] struct {
+	node *Node[K, V]
+	span int // level 0 steps to node
+}
+
+type Node[K constraints.Ordered, V any] struct {
+	key  K
+	val  V
+	next []link[K, V] // one link per level of the node
+}
+
+type SkipListRBT[K constraints.Ordered, V any] struct {
+	codec.Codecs[K, V] // key and value codecs for MarshalBinary
+
+	head  *Node[K, V] // a sentinel before the first key, with MaxLevel links
+	level int         // levels in use
+	size  int
+	rng   *rand.Rand
+}
+
+// create a list with randomly seeded levels
+func NewRBT[K constraints.Ordered, V any]() *SkipListRBT[K, V] {
+	return NewSeeded[K, V](rand.Int63())
+}
+
+// create a list whose node levels are drawn from seed, so the same sequence
+// of updates always builds the same list
+func NewSeeded[K constraints.Ordered, V any](seed int64) *SkipListRBT[K, V] {
+	return &SkipListRBT[K, V]{
+		head:  &Node[K, V]{next: make([]link[K, V], MaxLevel)},
+		level: 1,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (t *SkipListRBT[K, V]) IsEmpty() bool {
+	return t.size == 0
+}
+
+func (t *SkipListRBT[K, V]) Size() int {
+	return t.size
+}
+
+// the number of levels in use
+func (t *SkipListRBT[K, V]) Level() int {
+	return t.level
+}
+
+// a level in [1..MaxLevel], each level up with probability 1/4
+func (t *SkipListRBT[K, V]) randomLevel() int {
+	level := 1
+	for level < MaxLevel && t.rng.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
+
+// get the last node with a key < key on each level, and its rank counting
+// the head as 0
+func (t *SkipListRBT[K, V]) search(key K) (update [MaxLevel]*Node[K, V], rank [MaxLevel]int) {
+	x := t.head
+	r := 0
+	for i := t.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && x.next[i].node.key < key {
+			r += x.next[i].span
+			x = x.next[i].node
+		}
+		update[i] = x
+		rank[i] = r
+	}
+	return update, rank
+}
+
+// get the first node with a key >= key
+func (t *SkipListRBT[K, V]) ceiling(key K) *Node[K, V] {
+	x := t.head
+	for i := t.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && x.next[i].node.key < key {
+			x = x.next[i].node
+		}
+	}
+	return x.next[0].node
+}
+
+// get the last node with a key <= key, nil if there is none
+func (t *SkipListRBT[K, V]) floor(key K) *Node[K, V] {
+	x := t.head
+	for i := t.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && x.next[i].node.key <= key {
+			x = x.next[i].node
+		}
+	}
+	if x == t.head {
+		return nil
+	}
+	return x
+}
+
+func (t *SkipListRBT[K, V]) Get(key K) (V, bool) {
+	x := t.ceiling(key)
+	if x == nil || x.key != key {
+		var zero V
+		return zero, false
+	}
+	return x.val, true
+}
+
+func (t *SkipListRBT[K, V]) Contains(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// insert key or replace its value
+func (t *SkipListRBT[K, V]) Put(key K, val V) {
+	update, rank := t.search(key)
+	if x := update[0].next[0].node; x != nil && x.key == key {
+		x.val = val
+		return
+	}
+
+	level := t.randomLevel()
+	for i := t.level; i < level; i++ {
+		update[i] = t.head
+		rank[i] = 0
+		t.head.next[i] = link[K, V]{span: t.size}
+	}
+	t.level = max(t.level, level)
+
+	x := &Node[K, V]{key: key, val: val, next: make([]link[K, V], level)}
+	for i := 0; i < level; i++ {
+		// x lands rank[0]-rank[i] steps past update[i]
+		d := rank[0] - rank[i]
+		x.next[i] = link[K, V]{node: update[i].next[i].node, span: update[i].next[i].span - d}
+		update[i].next[i] = link[K, V]{node: x, span: d + 1}
+	}
+	for i := level; i < t.level; i++ {
+		update[i].next[i].span++
+	}
+	t.size++
+}
+
+func (t *SkipListRBT[K, V]) Delete(key K) {
+	update, _ := t.search(key)
+	x := update[0].next[0].node
+	if x == nil || x.key != key {
+		return
+	}
+	for i := 0; i < t.level; i++ {
+		if update[i].next[i].node == x {
+			update[i].next[i] = link[K, V]{node: x.next[i].node, span: update[i].next[i].span + x.next[i].span - 1}
+		} else {
+			update[i].next[i].span--
+		}
+	}
+	for t.level > 1 && t.head.next[t.level-1].node == nil {
+		t.level--
+	}
+	t.size--
+}
+
+func (t *SkipListRBT[K, V]) DeleteMin() {
+	if k, ok := t.Min(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *SkipListRBT[K, V]) DeleteMax() {
+	if k, ok := t.Max(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *SkipListRBT[K, V]) Min() (K, bool) {
+	x := t.head.next[0].node
+	if x == nil {
+		var zero K
+		return zero, false
+	}
+	return x.key, true
+}
+
+func (t *SkipListRBT[K, V]) Max() (K, bool) {
+	x := t.head
+	for i := t.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil {
+			x = x.next[i].node
+		}
+	}
+	if x == t.head {
+		var zero K
+		return zero, false
+	}
+	return x.key, true
+}
+
+// the largest key <= key
+func (t *SkipListRBT[K, V]) Floor(key K) (K, bool) {
+	x := t.floor(key)
+	if x == nil {
+		var zero K
+		return zero, false
+	}
+	return x.key, true
+}
+
+// the smallest key >= key
+func (t *SkipListRBT[K, V]) Ceiling(key K) (K, bool) {
+	x := t.ceiling(key)
+	if x == nil {
+		var zero K
+		return zero, false
+	}
+	return x.key, true
+}
+
+// the key of rank k, the k+1th smallest key
+func (t *SkipListRBT[K, V]) Select(k int) (K, bool) {
+	if k < 0 || k >= t.size {
+		var zero K
+		return zero, false
+	}
+	x := t.head
+	r := 0
+	for i := t.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && r+x.next[i].span <= k+1 {
+			r += x.next[i].span
+			x = x.next[i].node
+		}
+		if r == k+1 {
+			break
+		}
+	}
+	return x.key, true
+}
+
+// the number of keys less than key
+func (t *SkipListRBT[K, V]) Rank(key K) int {
+	_, rank := t.search(key)
+	return rank[0]
+}
+
+// iterate over the keys in [lo..hi] in order, starting from a search for lo
+func (t *SkipListRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for x := t.ceiling(lo); x != nil && x.key <= hi; x = x.next[0].node {
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+		}
+	}
+}
+
+func (t *SkipListRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, t.size)
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (t *SkipListRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for x := t.head.next[0].node; x != nil; x = x.next[0].node {
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package skiplist
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/codec"
+)
+
+func TestEmptyRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+func TestPutOneRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	if rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want false", rbt.IsEmpty())
+	}
+
+	v, ok := rbt.Get(1)
+	if !ok {
+		t.Errorf("Get(1) = %v; want 'one'", v)
+	}
+	if v != "one" {
+		t.Errorf("Get(1) = %v; want 'one'", v)
+	}
+}
+
+func TestPutThreeRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+	if rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want false", rbt.IsEmpty())
+	}
+}
+
+func TestContains3Rbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+
+	if x, ok := rbt.Get(1); !ok {
+		t.Errorf("Get(1) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(2); !ok {
+		t.Errorf("Get(2) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(3); !ok {
+		t.Errorf("Get(3) == %v; want true : %v", x, ok)
+	}
+
+	if x, ok := rbt.Get(4); ok {
+		t.Errorf("Get(4) == %v; want false", x)
+	}
+}
+
+// test that the keys are returned in order
+func TestContainsKeysRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	rbt.Put(1, "one")
+	rbt.Put(2, "two")
+	rbt.Put(3, "three")
+
+	v, ok := rbt.Get(1)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'one'", 1, v)
+	}
+	if v != "one" {
+		t.Errorf("Get(%v) = %v; want 'one'", 1, v)
+	}
+
+	v, ok = rbt.Get(2)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'two'", 2, v)
+	}
+	if v != "two" {
+		t.Errorf("Get(%v) = %v; want 'two'", 2, v)
+	}
+
+	v, ok = rbt.Get(3)
+	if !ok {
+		t.Errorf("Get(%v) = %v; want 'three'", 3, v)
+	}
+	if v != "three" {
+		t.Errorf("Get(%v) = %v; want 'three'", 3, v)
+	}
+
+}
+
+// create a map of random keys and values
+func TestRandomKeystRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	keys := make([]int, 100)
+	values := make([]string, 100)
+	for i := range keys {
+		keys[i] = rand.Intn(100)
+		values[i] = strconv.Itoa(keys[i])
+	}
+
+	for i := 0; i < len(keys); i++ {
+		rbt.Put(keys[i], values[i])
+	}
+
+	for i := 0; i < len(keys); i++ {
+		v, ok := rbt.Get(keys[i])
+		if !ok {
+			t.Errorf("Get(%v) = %v; want %v", keys[i], v, values[i])
+		}
+		if v != values[i] {
+			t.Errorf("Get(%v) = %v; want %v", keys[i], v, values[i])
+		}
+	}
+}
+
+// test the Iterator with a large number of random keys
+func TestIteratorRandom(t *testing.T) {
+	rbt := NewRBT[int, string]()
+
+	m := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		k := rand.Intn(100)
+		v := strconv.Itoa(k)
+		m[k] = v
+	}
+
+	// iterate over the map m
+	t.Log("--- random keys")
+	for k, v := range m {
+		t.Log(k, v)
+		rbt.Put(k, v)
+	}
+
+	k := -1
+	for r := range rbt.Iterator() {
+		if r.Key < k {
+			t.Errorf("Out of order(%v) = %v; ", k, r.Key)
+		}
+		t.Log(r.Key, r.Val)
+		k = r.Key
+	}
+}
+
+// test PrefixScan and LongestPrefixOf on path-like keys
+func TestPrefix(t *testing.T) {
+	rbt := NewRBT[string, int]()
+	paths := []string{"/", "/usr", "/usr/bin", "/usr/bin/go", "/usr/lib", "/usrx", "/var", "/var/log"}
+	for i, p := range paths {
+		rbt.Put(p, i)
+	}
+
+	want := []string{"/usr/bin", "/usr/bin/go", "/usr/lib"}
+	got := make([]string, 0)
+	for r := range PrefixScan(rbt, "/usr/") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("PrefixScan(/usr/) = %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	for r := range PrefixScan(rbt, "") {
+		got = append(got, r.Key)
+	}
+	if !slices.Equal(got, paths) {
+		t.Errorf("PrefixScan() = %v; want %v", got, paths)
+	}
+
+	tests := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"/usr/bin/gofmt", "/usr/bin/go", true},
+		{"/usr/bin", "/usr/bin", true},
+		{"/usr/local/bin", "/usr", true},
+		{"/usrx/y", "/usrx", true},
+		{"/tmp", "/", true},
+		{"tmp", "", false},
+	}
+	for _, tc := range tests {
+		k, ok := LongestPrefixOf(rbt, tc.s)
+		if k != tc.want || ok != tc.ok {
+			t.Errorf("LongestPrefixOf(%v) = %v, %v; want %v, %v", tc.s, k, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// test DeleteMin and DeleteMax against a sorted slice of random keys
+func TestDeleteMinMax(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	keys := rand.Perm(1000)
+	for _, k := range keys {
+		rbt.Put(k, strconv.Itoa(k))
+	}
+	slices.Sort(keys)
+
+	for len(keys) > 0 {
+		if rand.Intn(2) == 0 {
+			if k, ok := rbt.Min(); !ok || k != keys[0] {
+				t.Fatalf("Min() = %v, %v; want %v", k, ok, keys[0])
+			}
+			rbt.DeleteMin()
+			keys = keys[1:]
+		} else {
+			if k, ok := rbt.Max(); !ok || k != keys[len(keys)-1] {
+				t.Fatalf("Max() = %v, %v; want %v", k, ok, keys[len(keys)-1])
+			}
+			rbt.DeleteMax()
+			keys = keys[:len(keys)-1]
+		}
+		if rbt.Size() != len(keys) {
+			t.Fatalf("Size() = %v; want %v", rbt.Size(), len(keys))
+		}
+	}
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+// test Get on an empty tree
+func TestGetEmptyRbt(t *testing.T) {
+	rbt := NewRBT[int, string]()
+	if v, ok := rbt.Get(1); ok || v != "" {
+		t.Errorf("Get(1) = %v, %v; want '', false", v, ok)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		src := NewRBT[int, string]()
+		for _, k := range rand.Perm(n) {
+			src.Put(k, strconv.Itoa(k))
+		}
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewRBT[int, string]()
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		gdst := NewRBT[int, string]()
+		if err := gob.NewDecoder(&buf).Decode(gdst); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(dst.GetAll(), src.GetAll()) || !slices.Equal(gdst.GetAll(), src.GetAll()) {
+			t.Fatalf("n = %v: GetAll() = %v; want %v", n, dst.GetAll(), src.GetAll())
+		}
+
+		// the rebuilt tree must stay balanced under further updates
+		dst.Put(n, "new")
+		dst.DeleteMin()
+		dst.DeleteMax()
+		if dst.Size() != max(n-1, 0) {
+			t.Fatalf("n = %v: Size() = %v after updates", n, dst.Size())
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	words := NewRBT[string, int]()
+	for i, w := range []string{"pear", "apple", "fig"} {
+		words.Put(w, i)
+	}
+	data, err := json.Marshal(words)
+	if err != nil || string(data) != `{"apple":1,"fig":2,"pear":0}` {
+		t.Fatalf("json.Marshal() = %s, %v", data, err)
+	}
+
+	var doc struct{ Nums *SkipListRBT[int, string] }
+	doc.Nums = NewRBT[int, string]()
+	if err := json.Unmarshal([]byte(`{"Nums": [[3,"c"], [1,"a"], [2,"b"]]}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "c"}
+	for i, r := range doc.Nums.GetAll() {
+		if r.Key != i+1 || r.Val != want[i] {
+			t.Errorf("GetAll()[%v] = %v", i, r)
+		}
+	}
+	if doc.Nums.Size() != 3 {
+		t.Errorf("Size() = %v; want 3", doc.Nums.Size())
+	}
+
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(500) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	data, _ = json.Marshal(src)
+	dst := NewRBT[int, string]()
+	if err := json.Unmarshal(data, dst); err != nil || !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Errorf("json round trip: %v", err)
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	src := NewRBT[int, string]()
+	for _, k := range rand.Perm(1000) {
+		src.Put(k, strconv.Itoa(k))
+	}
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf, codec.NewBinaryEncoder[int, string](nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	dst, _, err := ReadFrom[int, string](&buf, codec.NewBinaryDecoder[int, string](nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dst.GetAll(), src.GetAll()) {
+		t.Fatalf("ReadFrom() does not match the source")
+	}
+	dst.DeleteMin()
+	if dst.Size() != 999 {
+		t.Errorf("Size() = %v; want 999", dst.Size())
+	}
+}
+
+// check that the keys are sorted on every level, that each level is a
+// subset of the one below and that every span matches the level 0 steps
+func checkList[K constraints.Ordered, V any](t *testing.T, s *SkipListRBT[K, V]) {
+	rank := make(map[*Node[K, V]]int)
+	r := 0
+	for x := s.head.next[0].node; x != nil; x = x.next[0].node {
+		r++
+		rank[x] = r
+	}
+	if r != s.size {
+		t.Fatalf("%v nodes on level 0; Size() = %v", r, s.size)
+	}
+	for i := 0; i < s.level; i++ {
+		x := s.head
+		for {
+			l := x.next[i]
+			if l.node == nil {
+				if l.span != s.size-rank[x] {
+					t.Fatalf("level %v: last span = %v; want %v", i, l.span, s.size-rank[x])
+				}
+				break
+			}
+			if x != s.head && l.node.key <= x.key {
+				t.Fatalf("level %v: %v after %v", i, l.node.key, x.key)
+			}
+			if _, ok := rank[l.node]; !ok {
+				t.Fatalf("level %v: %v not on level 0", i, l.node.key)
+			}
+			if l.span != rank[l.node]-rank[x] {
+				t.Fatalf("level %v: span to %v = %v; want %v", i, l.node.key, l.span, rank[l.node]-rank[x])
+			}
+			x = l.node
+		}
+	}
+	if s.level > 1 && s.head.next[s.level-1].node == nil {
+		t.Fatalf("level %v is empty", s.level)
+	}
+}
+
+func TestDeleteRandom(t *testing.T) {
+	list := NewSeeded[int, string](1)
+	m := make(map[int]string)
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(1000)
+		switch rand.Intn(4) {
+		case 0, 1:
+			list.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			list.Delete(k)
+			delete(m, k)
+		case 3:
+			if lo, ok := list.Min(); ok {
+				delete(m, lo)
+			}
+			list.DeleteMin()
+		}
+		if list.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", list.Size(), len(m))
+		}
+		if i%100 == 0 {
+			checkList(t, list)
+		}
+	}
+	checkList(t, list)
+	for k, v := range m {
+		if x, ok := list.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	list := NewSeeded[int, string](2)
+	for _, k := range rand.Perm(1000) {
+		list.Put(3*k, strconv.Itoa(3*k))
+	}
+	checkList(t, list)
+	for i := 0; i < 1000; i++ {
+		if k, ok := list.Select(i); !ok || k != 3*i {
+			t.Fatalf("Select(%v) = %v, %v; want %v", i, k, ok, 3*i)
+		}
+		if r := list.Rank(3 * i); r != i {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i, r, i)
+		}
+		if r := list.Rank(3*i + 1); r != i+1 {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i+1, r, i+1)
+		}
+	}
+	if _, ok := list.Select(1000); ok {
+		t.Errorf("Select(1000) found a key")
+	}
+	if k, ok := list.Floor(100); !ok || k != 99 {
+		t.Errorf("Floor(100) = %v, %v; want 99", k, ok)
+	}
+	if k, ok := list.Ceiling(100); !ok || k != 102 {
+		t.Errorf("Ceiling(100) = %v, %v; want 102", k, ok)
+	}
+
+	for _, r := range [][2]int{{-5, -1}, {0, 0}, {10, 40}, {2990, 3100}, {-1, 3000}} {
+		var keys []int
+		for p := range list.Range(r[0], r[1]) {
+			keys = append(keys, p.Key)
+		}
+		var want []int
+		for k := (max(r[0], 0) + 2) / 3 * 3; k <= r[1] && k < 3000; k += 3 {
+			want = append(want, k)
+		}
+		if !slices.Equal(keys, want) {
+			t.Errorf("Range(%v, %v) = %v; want %v", r[0], r[1], keys, want)
+		}
+	}
+}
+
+// the same seed and updates must build the same levels
+func TestSeeded(t *testing.T) {
+	levels := func(seed int64) []int {
+		list := NewSeeded[int, int](seed)
+		for _, k := range rand.New(rand.NewSource(7)).Perm(500) {
+			list.Put(k, k)
+		}
+		var l []int
+		for x := list.head.next[0].node; x != nil; x = x.next[0].node {
+			l = append(l, len(x.next))
+		}
+		return l
+	}
+	if !slices.Equal(levels(42), levels(42)) {
+		t.Errorf("seed 42 built different lists")
+	}
+	if slices.Equal(levels(42), levels(43)) {
+		t.Errorf("seeds 42 and 43 built the same list")
+	}
+}
@@ -0,0 +1,329 @@
+package splay
+
+import (
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// a splay tree moves every key it looks up or inserts to the root, with
+// rotations that roughly halve the depth of the path it took. there is no
+// balance information; instead any sequence of m operations costs
+// O(m log n), and a key used often stays near the top, so skewed workloads
+// with a few hot keys run faster than on a balanced tree.
+// this is the top-down splay of Sleator and Tarjan, "Self-Adjusting Binary
+// Search Trees", JACM 1985, which needs no parent pointers or recursion.
+//
+// because lookups rewrite the tree, Get is a write. View gives a read mode
+// that never splays, for many goroutines reading at once.
+
+type Node[K constraints.Ordered, V any] struct {
+	key         K
+	val         V
+	left, right *Node[K, V]
+}
+
+type SplayRBT[K constraints.Ordered, V any] struct {
+	root *Node[K, V]
+	size int
+}
+
+func NewRBT[K constraints.Ordered, V any]() *SplayRBT[K, V] {
+	return &SplayRBT[K, V]{}
+}
+
+func (t *SplayRBT[K, V]) IsEmpty() bool {
+	return t.root == nil
+}
+
+func (t *SplayRBT[K, V]) Size() int {
+	return t.size
+}
+
+// height of the tree, -1 when it is empty. a splay tree can be as deep as
+// it is large, so this walks level by level instead of recursing
+func (t *SplayRBT[K, V]) Height() int {
+	h := -1
+	for level := []*Node[K, V]{t.root}; ; h++ {
+		var next []*Node[K, V]
+		for _, x := range level {
+			if x != nil {
+				next = append(next, x.left, x.right)
+			}
+		}
+		if next == nil {
+			return h
+		}
+		level = next
+	}
+}
+
+// splay the subtree of x on key, returning the new root. the root holds key
+// if it is present, otherwise the last node on the search path for key
+func splay[K constraints.Ordered, V any](x *Node[K, V], key K) *Node[K, V] {
+	if x == nil {
+		return nil
+	}
+	// header.right collects the tree of keys < key, header.left those > key
+	var header Node[K, V]
+	l, r := &header, &header
+	for {
+		if key < x.key {
+			if x.left == nil {
+				break
+			}
+			if key < x.left.key {
+				// zig-zig: rotate right
+				y := x.left
+				x.left = y.right
+				y.right = x
+				x = y
+				if x.left == nil {
+					break
+				}
+			}
+			// link right
+			r.left = x
+			r = x
+			x = x.left
+		} else if key > x.key {
+			if x.right == nil {
+				break
+			}
+			if key > x.right.key {
+				// zig-zig: rotate left
+				y := x.right
+				x.right = y.left
+				y.left = x
+				x = y
+				if x.right == nil {
+					break
+				}
+			}
+			// link left
+			l.right = x
+			l = x
+			x = x.right
+		} else {
+			break
+		}
+	}
+	// assemble
+	l.right = x.left
+	r.left = x.right
+	x.left = header.right
+	x.right = header.left
+	return x
+}
+
+// Get splays key to the root, so it modifies the tree. use View for
+// lookups from several goroutines
+func (t *SplayRBT[K, V]) Get(key K) (V, bool) {
+	t.root = splay(t.root, key)
+	if t.root == nil || t.root.key != key {
+		var zero V
+		return zero, false
+	}
+	return t.root.val, true
+}
+
+func (t *SplayRBT[K, V]) Contains(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// insert a key-value pair, replacing the value of an existing key. either
+// way key ends up at the root
+func (t *SplayRBT[K, V]) Put(key K, val V) {
+	if t.root == nil {
+		t.root = &Node[K, V]{key: key, val: val}
+		t.size++
+		return
+	}
+	t.root = splay(t.root, key)
+	if key == t.root.key {
+		t.root.val = val
+		return
+	}
+	n := &Node[K, V]{key: key, val: val}
+	// the root is the neighbour of key, split it to either side of n
+	if key < t.root.key {
+		n.left = t.root.left
+		n.right = t.root
+		t.root.left = nil
+	} else {
+		n.right = t.root.right
+		n.left = t.root
+		t.root.right = nil
+	}
+	t.root = n
+	t.size++
+}
+
+func (t *SplayRBT[K, V]) Delete(key K) {
+	t.root = splay(t.root, key)
+	if t.root == nil || t.root.key != key {
+		return
+	}
+	if t.root.left == nil {
+		t.root = t.root.right
+	} else {
+		// every key on the left is below key, so splaying on key brings
+		// the largest of them up with an empty right subtree
+		right := t.root.right
+		t.root = splay(t.root.left, key)
+		t.root.right = right
+	}
+	t.size--
+}
+
+func (t *SplayRBT[K, V]) DeleteMin() {
+	if k, ok := t.Min(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *SplayRBT[K, V]) DeleteMax() {
+	if k, ok := t.Max(); ok {
+		t.Delete(k)
+	}
+}
+
+// Min does not splay
+func (t *SplayRBT[K, V]) Min() (K, bool) {
+	return t.View().Min()
+}
+
+// Max does not splay
+func (t *SplayRBT[K, V]) Max() (K, bool) {
+	return t.View().Max()
+}
+
+func (t *SplayRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return t.View().Range(lo, hi)
+}
+
+func (t *SplayRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	return t.View().GetAll()
+}
+
+func (t *SplayRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return t.View().Iterator()
+}
+
+// View is a read-only handle on a tree whose lookups do not splay. nothing
+// it does writes to the tree, so any number of goroutines may read through
+// views at once, as long as no one calls a method of the tree itself,
+// including Get, at the same time
+type View[K constraints.Ordered, V any] struct {
+	t *SplayRBT[K, V]
+}
+
+func (t *SplayRBT[K, V]) View() View[K, V] {
+	return View[K, V]{t: t}
+}
+
+func (v View[K, V]) IsEmpty() bool {
+	return v.t.root == nil
+}
+
+func (v View[K, V]) Size() int {
+	return v.t.size
+}
+
+// a plain binary search, leaving the tree as it is
+func (v View[K, V]) Get(key K) (V, bool) {
+	for x := v.t.root; x != nil; {
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			x = x.right
+		} else {
+			return x.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func (v View[K, V]) Contains(key K) bool {
+	_, ok := v.Get(key)
+	return ok
+}
+
+func (v View[K, V]) Min() (K, bool) {
+	if v.t.root == nil {
+		var zero K
+		return zero, false
+	}
+	x := v.t.root
+	for x.left != nil {
+		x = x.left
+	}
+	return x.key, true
+}
+
+func (v View[K, V]) Max() (K, bool) {
+	if v.t.root == nil {
+		var zero K
+		return zero, false
+	}
+	x := v.t.root
+	for x.right != nil {
+		x = x.right
+	}
+	return x.key, true
+}
+
+// iterate over the keys in [lo..hi] in order. the walk keeps its own stack
+// of left spines since the tree may be too deep to recurse over
+func (v View[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var stack []*Node[K, V]
+		push := func(x *Node[K, V]) {
+			for x != nil {
+				if x.key < lo {
+					x = x.right
+				} else {
+					stack = append(stack, x)
+					x = x.left
+				}
+			}
+		}
+		push(v.t.root)
+		for len(stack) > 0 {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if x.key > hi || !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+			push(x.right)
+		}
+	}
+}
+
+func (v View[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, v.t.size)
+	for r := range v.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (v View[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var stack []*Node[K, V]
+		for x := v.t.root; x != nil || len(stack) > 0; {
+			if x != nil {
+				stack = append(stack, x)
+				x = x.left
+				continue
+			}
+			x = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(rbt.KeyValuePair[K, V]{Key: x.key, Val: x.val}) {
+				return
+			}
+			x = x.right
+		}
+	}
+}
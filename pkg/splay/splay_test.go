@@ -0,0 +1,205 @@
+package splay
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/internal/bench"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// check the keys are in order and count the nodes
+func checkBST[K constraints.Ordered, V any](t *testing.T, x *Node[K, V]) int {
+	if x == nil {
+		return 0
+	}
+	if x.left != nil && x.left.key >= x.key || x.right != nil && x.right.key <= x.key {
+		t.Fatalf("children of %v out of order", x.key)
+	}
+	return 1 + checkBST(t, x.left) + checkBST(t, x.right)
+}
+
+func TestDeleteRandom(t *testing.T) {
+	tree := NewRBT[int, string]()
+	m := make(map[int]string)
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(1000)
+		switch rand.Intn(5) {
+		case 0, 1:
+			tree.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			tree.Delete(k)
+			delete(m, k)
+		case 3:
+			if v, ok := tree.Get(k); ok != (m[k] != "") || v != m[k] {
+				t.Fatalf("Get(%v) = %v, %v; want %v", k, v, ok, m[k])
+			}
+		case 4:
+			if lo, ok := tree.Min(); ok {
+				delete(m, lo)
+			}
+			tree.DeleteMin()
+		}
+		if tree.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", tree.Size(), len(m))
+		}
+		if i%100 == 0 {
+			if n := checkBST(t, tree.root); n != len(m) {
+				t.Fatalf("%v nodes; want %v", n, len(m))
+			}
+		}
+	}
+	for k, v := range m {
+		if x, ok := tree.View().Get(k); !ok || x != v {
+			t.Errorf("View().Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+}
+
+// Get and Put bring the key to the root, View leaves the tree alone
+func TestSplay(t *testing.T) {
+	tree := NewRBT[int, int]()
+	for _, k := range rand.Perm(1000) {
+		tree.Put(k, k)
+		if tree.root.key != k {
+			t.Fatalf("Put(%v) left %v at the root", k, tree.root.key)
+		}
+	}
+	for _, k := range []int{500, 0, 999, 500} {
+		if v, ok := tree.Get(k); !ok || v != k || tree.root.key != k {
+			t.Fatalf("Get(%v) left %v at the root", k, tree.root.key)
+		}
+	}
+	tree.Get(-1)
+	if tree.root.key != 0 {
+		t.Errorf("Get(-1) left %v at the root; want 0", tree.root.key)
+	}
+	root := tree.root
+	for _, k := range []int{1, 2, 3, 998} {
+		tree.View().Get(k)
+	}
+	if tree.root != root {
+		t.Errorf("View().Get() splayed the tree")
+	}
+
+	// inserting in order makes a path, splaying its bottom halves the depth
+	tree = NewRBT[int, int]()
+	for k := 0; k < 1000; k++ {
+		tree.Put(k, k)
+	}
+	if h := tree.Height(); h != 999 {
+		t.Fatalf("Height() = %v after sorted puts; want 999", h)
+	}
+	tree.Get(0)
+	if h := tree.Height(); h > 510 {
+		t.Errorf("Height() = %v after Get(0); want about 500", h)
+	}
+}
+
+func TestView(t *testing.T) {
+	tree := NewRBT[int, string]()
+	for _, k := range rand.Perm(1000) {
+		tree.Put(2*k, strconv.Itoa(2*k))
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := tree.View()
+			for i := 0; i < 1000; i++ {
+				k := rand.Intn(2000)
+				if x, ok := v.Get(k); ok != (k%2 == 0) || ok && x != strconv.Itoa(k) {
+					t.Errorf("View().Get(%v) = %v, %v", k, x, ok)
+				}
+			}
+			var keys []int
+			for r := range v.Range(10, 20) {
+				keys = append(keys, r.Key)
+			}
+			if want := []int{10, 12, 14, 16, 18, 20}; !slices.Equal(keys, want) {
+				t.Errorf("Range(10, 20) = %v; want %v", keys, want)
+			}
+		}()
+	}
+	wg.Wait()
+	if k, ok := tree.View().Min(); !ok || k != 0 {
+		t.Errorf("Min() = %v", k)
+	}
+	if k, ok := tree.View().Max(); !ok || k != 1998 {
+		t.Errorf("Max() = %v", k)
+	}
+	if len(tree.GetAll()) != 1000 {
+		t.Errorf("GetAll() has %v pairs", len(tree.GetAll()))
+	}
+}
+
+// ************ zipf benchmarks against the LLRB trees ************
+
+const benchSize = 100000
+
+var impls = append([]bench.Impl[int]{
+	{Name: "splay", New: func() rbt.RBT[int, int] { return NewRBT[int, int]() }},
+}, bench.LLRB[int]()...)
+
+// draw keys from [0..n) with a zipf distribution of exponent s. a
+// permutation spreads the hot keys through the key space, otherwise the
+// hottest would also be the smallest
+func zipfKeys(n int, s float64, count int) []int {
+	r := rand.New(rand.NewSource(1))
+	perm := r.Perm(n)
+	z := rand.NewZipf(r, s, 1, uint64(n-1))
+	keys := make([]int, count)
+	for i := range keys {
+		keys[i] = perm[z.Uint64()]
+	}
+	return keys
+}
+
+// run op against each implementation filled with benchSize keys, for
+// uniform lookups and zipf exponents from mild to steep
+func benchmark(b *testing.B, op bench.Op[int]) {
+	fill := rand.Perm(benchSize)
+	for _, s := range []float64{0, 1.1, 1.5, 2} {
+		var keys []int
+		if s == 0 {
+			keys = rand.New(rand.NewSource(1)).Perm(benchSize)
+		} else {
+			keys = zipfKeys(benchSize, s, 1<<20)
+		}
+		bench.Run(b, impls, bench.Workload[int]{Name: fmt.Sprintf("s=%v", s), Fill: fill, Keys: keys}, op)
+	}
+}
+
+func BenchmarkZipfGet(b *testing.B) {
+	benchmark(b, bench.Get[int])
+}
+
+func BenchmarkZipfPut(b *testing.B) {
+	benchmark(b, bench.Put[int])
+}
+
+// lookups through a View do not splay, so the tree keeps whatever shape
+// the puts left it in
+func BenchmarkZipfViewGet(b *testing.B) {
+	for _, s := range []float64{1.1, 1.5, 2} {
+		keys := zipfKeys(benchSize, s, 1<<20)
+		b.Run(fmt.Sprintf("s=%v", s), func(b *testing.B) {
+			t := NewRBT[int, int]()
+			for _, k := range rand.Perm(benchSize) {
+				t.Put(k, k)
+			}
+			v := t.View()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v.Get(keys[i%len(keys)])
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package syncrbt
+
+import (
+	"sync"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// SyncRBT makes any rbt.RBT safe for concurrent use. Writers take an
+// exclusive lock, readers take a shared lock.
+//
+// Iterator ranges over a snapshot taken under the read lock, so the loop
+// body may call any method, including Put, without deadlocking and sees the
+// tree as it was when the range started. LockedIterator avoids the copy by
+// holding the read lock for the whole range; its loop body must not call
+// any SyncRBT method, not even a read, because sync.RWMutex blocks new
+// readers once a writer is waiting. View hands a function the underlying
+// tree under the read lock for reads that need a consistent view.
+type SyncRBT[K constraints.Ordered, V any] struct {
+	mu   sync.RWMutex
+	tree rbt.RBT[K, V]
+}
+
+// wrap an existing tree. the tree must not be used directly afterwards
+func NewRBT[K constraints.Ordered, V any](tree rbt.RBT[K, V]) *SyncRBT[K, V] {
+	return &SyncRBT[K, V]{tree: tree}
+}
+
+func (s *SyncRBT[K, V]) Put(key K, val V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Put(key, val)
+}
+
+func (s *SyncRBT[K, V]) Get(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Get(key)
+}
+
+func (s *SyncRBT[K, V]) IsEmpty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.IsEmpty()
+}
+
+func (s *SyncRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.GetAll()
+}
+
+// run fn with exclusive access to the underlying tree, for read-modify-write
+// sequences that must not interleave with other writers
+func (s *SyncRBT[K, V]) Update(fn func(tree rbt.RBT[K, V])) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.tree)
+}
+
+// run fn with shared access to the underlying tree. fn may read the tree,
+// including ranging over it, but must not write to it or call s
+func (s *SyncRBT[K, V]) View(fn func(tree rbt.RBT[K, V])) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.tree)
+}
+
+// iterate over a snapshot of the tree in order
+func (s *SyncRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		for _, r := range s.GetAll() {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// iterate over the live tree in order while holding the read lock.
+// writers block until the range finishes. the loop body must not call s:
+// a write deadlocks at once and a read deadlocks as soon as a writer is
+// queued, use View to read other keys while ranging
+func (s *SyncRBT[K, V]) LockedIterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		for r := range s.tree.Iterator() {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package syncrbt
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy.xyz/go-tree-iterator/avl"
+	ch "sqirvy.xyz/go-tree-iterator/chatgpt"
+	cp "sqirvy.xyz/go-tree-iterator/copilot"
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+var trees = []struct {
+	name string
+	new  func() rbt.RBT[int, int]
+}{
+	{"copilot", func() rbt.RBT[int, int] { return cp.NewRBT[int, int]() }},
+	{"gemini", func() rbt.RBT[int, int] { return gm.NewRBT[int, int]() }},
+	{"chatgpt", func() rbt.RBT[int, int] { return ch.NewRBT[int, int]() }},
+	{"avl", func() rbt.RBT[int, int] { return avl.NewRBT[int, int]() }},
+}
+
+// run writers, readers and both kinds of iterators at the same time.
+// run with -race to check the locking
+func TestConcurrentStress(t *testing.T) {
+	for _, tc := range trees {
+		s := NewRBT(tc.new())
+		var wg sync.WaitGroup
+
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					k := rand.Intn(1000)
+					s.Put(k, k*10)
+				}
+			}(w)
+		}
+
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					k := rand.Intn(1000)
+					if v, ok := s.Get(k); ok && v != k*10 {
+						t.Errorf("%v: Get(%v) = %v; want %v", tc.name, k, v, k*10)
+					}
+				}
+			}()
+		}
+
+		for r := 0; r < 2; r++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					checkOrder(t, tc.name, s.Iterator())
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					checkOrder(t, tc.name, s.LockedIterator())
+				}
+			}()
+		}
+
+		wg.Wait()
+	}
+}
+
+func checkOrder(t *testing.T, name string, it func(func(rbt.KeyValuePair[int, int]) bool)) {
+	k := -1
+	for r := range it {
+		if r.Key <= k {
+			t.Errorf("%v: out of order %v after %v", name, r.Key, k)
+		}
+		k = r.Key
+	}
+}
+
+// test that the snapshot Iterator allows writes from the loop body
+func TestIteratorWrite(t *testing.T) {
+	s := NewRBT(gm.NewRBT[int, int]())
+	for i := 0; i < 10; i++ {
+		s.Put(i, i)
+	}
+
+	n := 0
+	for r := range s.Iterator() {
+		s.Put(r.Key+100, r.Val)
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Iterator() visited %v keys; want 10", n)
+	}
+	if got := len(s.GetAll()); got != 20 {
+		t.Errorf("len(GetAll()) = %v; want 20", got)
+	}
+}
+
+// test that View can read the tree while a writer is queued behind it, the
+// case where a read through s from a LockedIterator loop body deadlocks
+func TestViewQueuedWriter(t *testing.T) {
+	s := NewRBT(gm.NewRBT[int, int]())
+	for i := 0; i < 10; i++ {
+		s.Put(i, i)
+	}
+
+	done := make(chan struct{})
+	s.View(func(tree rbt.RBT[int, int]) {
+		go func() {
+			s.Put(100, 100)
+			close(done)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		for r := range tree.Iterator() {
+			if v, ok := tree.Get(r.Key); !ok || v != r.Val {
+				t.Errorf("Get(%v) = %v, %v", r.Key, v, ok)
+			}
+		}
+		select {
+		case <-done:
+			t.Errorf("Put finished while View held the read lock")
+		default:
+		}
+	})
+	<-done
+	if _, ok := s.Get(100); !ok {
+		t.Errorf("Get(100) after View: not found")
+	}
+}
+
+// test that Update runs read-modify-write sequences atomically
+func TestUpdate(t *testing.T) {
+	s := NewRBT(cp.NewRBT[string, int]())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Update(func(tree rbt.RBT[string, int]) {
+					v, _ := tree.Get("count")
+					tree.Put("count", v+1)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := s.Get("count"); v != 8000 {
+		t.Errorf("Get(count) = %v; want 8000", v)
+	}
+}
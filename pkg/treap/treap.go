@@ -0,0 +1,383 @@
+package treap
+
+import (
+	"errors"
+	"math/rand"
+
+	"golang.org/x/exp/constraints"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// a treap is a binary search tree on the keys and a max-heap on random
+// priorities, so its shape is that of a tree built by inserting the keys
+// in random order and its expected depth is O(log n).
+//
+// split and merge are the core operations: split cuts a treap into the keys
+// below a pivot and the rest, merge joins two treaps whose keys do not
+// overlap. both walk one path, so cutting out or splicing in a whole range
+// costs O(log n) expected no matter how many keys it holds. each node also
+// counts the nodes in its subtree for Select and Rank.
+
+// ErrOverlap is returned by Merge when the keys of the two treaps interleave
+var ErrOverlap = errors.New("treap: merged keys overlap")
+
+type Node[K constraints.Ordered, V any] struct {
+	key         K
+	val         V
+	prio        uint64
+	size        int // number of nodes in the subtree
+	left, right *Node[K, V]
+}
+
+type TreapRBT[K constraints.Ordered, V any] struct {
+	root *Node[K, V]
+	rng  *rand.Rand
+}
+
+// create a treap with randomly seeded priorities
+func NewRBT[K constraints.Ordered, V any]() *TreapRBT[K, V] {
+	return NewSeeded[K, V](rand.Int63())
+}
+
+// create a treap whose priorities are drawn from seed, so the same sequence
+// of updates always builds the same tree
+func NewSeeded[K constraints.Ordered, V any](seed int64) *TreapRBT[K, V] {
+	return &TreapRBT[K, V]{rng: rand.New(rand.NewSource(seed))}
+}
+
+// wrap root in a new treap that draws its priorities from a source seeded by t
+func (t *TreapRBT[K, V]) spawn(root *Node[K, V]) *TreapRBT[K, V] {
+	return &TreapRBT[K, V]{root: root, rng: rand.New(rand.NewSource(t.rng.Int63()))}
+}
+
+func (t *TreapRBT[K, V]) IsEmpty() bool {
+	return t.root == nil
+}
+
+func (t *TreapRBT[K, V]) Size() int {
+	return size(t.root)
+}
+
+func size[K constraints.Ordered, V any](x *Node[K, V]) int {
+	if x == nil {
+		return 0
+	}
+	return x.size
+}
+
+func update[K constraints.Ordered, V any](x *Node[K, V]) {
+	x.size = 1 + size(x.left) + size(x.right)
+}
+
+// height of the tree, -1 when it is empty
+func (t *TreapRBT[K, V]) Height() int {
+	var height func(*Node[K, V]) int
+	height = func(x *Node[K, V]) int {
+		if x == nil {
+			return -1
+		}
+		return 1 + max(height(x.left), height(x.right))
+	}
+	return height(t.root)
+}
+
+// split the subtree of x into the keys < key and the keys >= key
+func split[K constraints.Ordered, V any](x *Node[K, V], key K) (*Node[K, V], *Node[K, V]) {
+	if x == nil {
+		return nil, nil
+	}
+	if x.key < key {
+		l, r := split(x.right, key)
+		x.right = l
+		update(x)
+		return x, r
+	}
+	l, r := split(x.left, key)
+	x.left = r
+	update(x)
+	return l, x
+}
+
+// join two subtrees where every key in a is less than every key in b
+func merge[K constraints.Ordered, V any](a *Node[K, V], b *Node[K, V]) *Node[K, V] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.prio > b.prio {
+		a.right = merge(a.right, b)
+		update(a)
+		return a
+	}
+	b.left = merge(a, b.left)
+	update(b)
+	return b
+}
+
+func (t *TreapRBT[K, V]) Get(key K) (V, bool) {
+	for x := t.root; x != nil; {
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			x = x.right
+		} else {
+			return x.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func (t *TreapRBT[K, V]) Contains(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// insert a key-value pair, replacing the value of an existing key. a new
+// key splits the tree at key and merges itself in between the halves
+func (t *TreapRBT[K, V]) Put(key K, val V) {
+	for x := t.root; x != nil; {
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			x = x.right
+		} else {
+			x.val = val
+			return
+		}
+	}
+	l, r := split(t.root, key)
+	x := &Node[K, V]{key: key, val: val, prio: t.rng.Uint64(), size: 1}
+	t.root = merge(merge(l, x), r)
+}
+
+func (t *TreapRBT[K, V]) Delete(key K) {
+	t.root = remove(t.root, key)
+}
+
+// remove key from the subtree of x by merging the children of its node
+func remove[K constraints.Ordered, V any](x *Node[K, V], key K) *Node[K, V] {
+	if x == nil {
+		return nil
+	}
+	if key < x.key {
+		x.left = remove(x.left, key)
+	} else if key > x.key {
+		x.right = remove(x.right, key)
+	} else {
+		return merge(x.left, x.right)
+	}
+	update(x)
+	return x
+}
+
+func (t *TreapRBT[K, V]) DeleteMin() {
+	if k, ok := t.Min(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *TreapRBT[K, V]) DeleteMax() {
+	if k, ok := t.Max(); ok {
+		t.Delete(k)
+	}
+}
+
+func (t *TreapRBT[K, V]) Min() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	x := t.root
+	for x.left != nil {
+		x = x.left
+	}
+	return x.key, true
+}
+
+func (t *TreapRBT[K, V]) Max() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	x := t.root
+	for x.right != nil {
+		x = x.right
+	}
+	return x.key, true
+}
+
+// Split moves the keys >= key into a new treap and leaves the keys < key in t
+func (t *TreapRBT[K, V]) Split(key K) *TreapRBT[K, V] {
+	l, r := split(t.root, key)
+	t.root = l
+	return t.spawn(r)
+}
+
+// Merge moves every key of other into t, leaving other empty. the keys of
+// other must all be greater than the keys of t, otherwise neither treap
+// changes and Merge returns ErrOverlap. use Union for treaps that overlap
+func (t *TreapRBT[K, V]) Merge(other *TreapRBT[K, V]) error {
+	hi, ok1 := t.Max()
+	lo, ok2 := other.Min()
+	if ok1 && ok2 && hi >= lo {
+		return ErrOverlap
+	}
+	t.root = merge(t.root, other.root)
+	other.root = nil
+	return nil
+}
+
+// Cut removes the keys in [lo..hi) from t and returns them as a new treap
+func (t *TreapRBT[K, V]) Cut(lo K, hi K) *TreapRBT[K, V] {
+	return t.spawn(t.cut(lo, hi))
+}
+
+// DeleteRange removes the keys in [lo..hi) and returns how many there were
+func (t *TreapRBT[K, V]) DeleteRange(lo K, hi K) int {
+	return size(t.cut(lo, hi))
+}
+
+// split out the keys in [lo..hi) and merge the rest back together
+func (t *TreapRBT[K, V]) cut(lo K, hi K) *Node[K, V] {
+	if hi <= lo {
+		return nil
+	}
+	l, r := split(t.root, lo)
+	m, r := split(r, hi)
+	t.root = merge(l, r)
+	return m
+}
+
+// Union moves every key of other into t, leaving other empty. where both
+// hold a key the value from other wins. joining m keys into n costs
+// O(m log(n/m + 1)) expected, so a bulk load into a large treap is cheap
+func (t *TreapRBT[K, V]) Union(other *TreapRBT[K, V]) {
+	t.root = union(t.root, other.root, true)
+	other.root = nil
+}
+
+// join the subtrees of a and b, which may share keys. if bWins the values in
+// b replace those in a
+func union[K constraints.Ordered, V any](a *Node[K, V], b *Node[K, V], bWins bool) *Node[K, V] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	// the root with the higher priority stays on top
+	if a.prio < b.prio {
+		a, b = b, a
+		bWins = !bWins
+	}
+	l, r := split(b, a.key)
+	if r != nil {
+		var dup *Node[K, V]
+		if r, dup = popMin(r); dup.key != a.key {
+			r = merge(dup, r)
+		} else if bWins {
+			a.val = dup.val
+		}
+	}
+	a.left = union(a.left, l, bWins)
+	a.right = union(a.right, r, bWins)
+	update(a)
+	return a
+}
+
+// remove the smallest node from the subtree of x, returning what is left
+// and the detached node
+func popMin[K constraints.Ordered, V any](x *Node[K, V]) (*Node[K, V], *Node[K, V]) {
+	if x.left == nil {
+		r := x.right
+		x.right = nil
+		update(x)
+		return r, x
+	}
+	var m *Node[K, V]
+	x.left, m = popMin(x.left)
+	update(x)
+	return x, m
+}
+
+// the key of rank k, the k+1th smallest key
+func (t *TreapRBT[K, V]) Select(k int) (K, bool) {
+	if k < 0 || k >= t.Size() {
+		var zero K
+		return zero, false
+	}
+	x := t.root
+	for {
+		l := size(x.left)
+		if k < l {
+			x = x.left
+		} else if k > l {
+			k -= l + 1
+			x = x.right
+		} else {
+			return x.key, true
+		}
+	}
+}
+
+// the number of keys less than key
+func (t *TreapRBT[K, V]) Rank(key K) int {
+	r := 0
+	for x := t.root; x != nil; {
+		if key < x.key {
+			x = x.left
+		} else if key > x.key {
+			r += size(x.left) + 1
+			x = x.right
+		} else {
+			return r + size(x.left)
+		}
+	}
+	return r
+}
+
+// iterate over the keys in [lo..hi] in order, skipping subtrees outside the range
+func (t *TreapRBT[K, V]) Range(lo K, hi K) func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			if lo < n.key && !inorder(n.left) {
+				return false
+			}
+			if lo <= n.key && n.key <= hi && !yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) {
+				return false
+			}
+			return hi <= n.key || inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
+
+func (t *TreapRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0, t.Size())
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+func (t *TreapRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		var inorder func(*Node[K, V]) bool
+		inorder = func(n *Node[K, V]) bool {
+			if n == nil {
+				return true
+			}
+			return inorder(n.left) &&
+				yield(rbt.KeyValuePair[K, V]{Key: n.key, Val: n.val}) &&
+				inorder(n.right)
+		}
+		inorder(t.root)
+	}
+}
@@ -0,0 +1,204 @@
+package treap
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/exp/constraints"
+)
+
+// check key order, heap order on the priorities and subtree sizes
+func checkTreap[K constraints.Ordered, V any](t *testing.T, x *Node[K, V]) {
+	if x == nil {
+		return
+	}
+	if l := x.left; l != nil && (l.key >= x.key || l.prio > x.prio) {
+		t.Fatalf("left child %v out of order under %v", l.key, x.key)
+	}
+	if r := x.right; r != nil && (r.key <= x.key || r.prio > x.prio) {
+		t.Fatalf("right child %v out of order under %v", r.key, x.key)
+	}
+	if x.size != 1+size(x.left)+size(x.right) {
+		t.Fatalf("size of %v = %v", x.key, x.size)
+	}
+	checkTreap(t, x.left)
+	checkTreap(t, x.right)
+}
+
+func keys[K constraints.Ordered, V any](t *TreapRBT[K, V]) []K {
+	k := make([]K, 0)
+	for r := range t.Iterator() {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+func TestDeleteRandom(t *testing.T) {
+	tree := NewSeeded[int, string](1)
+	m := make(map[int]string)
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(1000)
+		switch rand.Intn(4) {
+		case 0, 1:
+			tree.Put(k, strconv.Itoa(k))
+			m[k] = strconv.Itoa(k)
+		case 2:
+			tree.Delete(k)
+			delete(m, k)
+		case 3:
+			if hi, ok := tree.Max(); ok {
+				delete(m, hi)
+			}
+			tree.DeleteMax()
+		}
+		if tree.Size() != len(m) {
+			t.Fatalf("Size() = %v; want %v", tree.Size(), len(m))
+		}
+		if i%100 == 0 {
+			checkTreap(t, tree.root)
+		}
+	}
+	checkTreap(t, tree.root)
+	for k, v := range m {
+		if x, ok := tree.Get(k); !ok || x != v {
+			t.Errorf("Get(%v) = %v; want %v", k, x, v)
+		}
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	tree := NewSeeded[int, string](2)
+	for _, k := range rand.Perm(1000) {
+		tree.Put(3*k, strconv.Itoa(3*k))
+	}
+	for i := 0; i < 1000; i++ {
+		if k, ok := tree.Select(i); !ok || k != 3*i {
+			t.Fatalf("Select(%v) = %v, %v; want %v", i, k, ok, 3*i)
+		}
+		if r := tree.Rank(3*i + 1); r != i+1 {
+			t.Fatalf("Rank(%v) = %v; want %v", 3*i+1, r, i+1)
+		}
+	}
+	var got []int
+	for p := range tree.Range(10, 40) {
+		got = append(got, p.Key)
+	}
+	if want := []int{12, 15, 18, 21, 24, 27, 30, 33, 36, 39}; !slices.Equal(got, want) {
+		t.Errorf("Range(10, 40) = %v; want %v", got, want)
+	}
+}
+
+func TestSplitMerge(t *testing.T) {
+	tree := NewSeeded[int, int](3)
+	for _, k := range rand.Perm(1000) {
+		tree.Put(k, k)
+	}
+	for _, pivot := range []int{-1, 0, 1, 500, 999, 1000, 2000} {
+		hi := tree.Split(pivot)
+		checkTreap(t, tree.root)
+		checkTreap(t, hi.root)
+		lo := min(max(pivot, 0), 1000)
+		if tree.Size() != lo || hi.Size() != 1000-lo {
+			t.Fatalf("Split(%v) sizes = %v, %v", pivot, tree.Size(), hi.Size())
+		}
+		if k, ok := hi.Min(); ok && k != lo {
+			t.Fatalf("Split(%v) right starts at %v", pivot, k)
+		}
+		if err := tree.Merge(hi); err != nil {
+			t.Fatal(err)
+		}
+		checkTreap(t, tree.root)
+		if tree.Size() != 1000 || !hi.IsEmpty() {
+			t.Fatalf("Merge() size = %v", tree.Size())
+		}
+	}
+
+	other := NewSeeded[int, int](4)
+	other.Put(500, 0)
+	if err := tree.Merge(other); err != ErrOverlap {
+		t.Errorf("Merge() = %v; want ErrOverlap", err)
+	}
+	if tree.Size() != 1000 || other.Size() != 1 {
+		t.Errorf("a failed Merge changed the treaps")
+	}
+}
+
+func TestCut(t *testing.T) {
+	tree := NewSeeded[int, int](5)
+	for _, k := range rand.Perm(100) {
+		tree.Put(k, k)
+	}
+	cut := tree.Cut(10, 20)
+	checkTreap(t, tree.root)
+	checkTreap(t, cut.root)
+	if want := []int{10, 11, 12, 13, 14, 15, 16, 17, 18, 19}; !slices.Equal(keys(cut), want) {
+		t.Errorf("Cut(10, 20) = %v; want %v", keys(cut), want)
+	}
+	if tree.Size() != 90 || tree.Contains(10) || !tree.Contains(20) || !tree.Contains(9) {
+		t.Errorf("Cut(10, 20) left %v", keys(tree))
+	}
+	if n := tree.DeleteRange(0, 50); n != 40 {
+		t.Errorf("DeleteRange(0, 50) = %v; want 40", n)
+	}
+	if n := tree.DeleteRange(60, 60); n != 0 {
+		t.Errorf("DeleteRange(60, 60) = %v; want 0", n)
+	}
+	if k, ok := tree.Min(); !ok || k != 50 || tree.Size() != 50 {
+		t.Errorf("Min() = %v after DeleteRange, Size() = %v", k, tree.Size())
+	}
+}
+
+func TestUnion(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		a := NewSeeded[int, string](int64(i))
+		b := NewSeeded[int, string](int64(-i))
+		m := make(map[int]string)
+		for _, k := range rand.Perm(300)[:rand.Intn(300)] {
+			a.Put(k, "a")
+			m[k] = "a"
+		}
+		for _, k := range rand.Perm(300)[:rand.Intn(300)] {
+			b.Put(k, "b")
+			m[k] = "b"
+		}
+		a.Union(b)
+		checkTreap(t, a.root)
+		if a.Size() != len(m) || !b.IsEmpty() {
+			t.Fatalf("Union() size = %v; want %v", a.Size(), len(m))
+		}
+		for k, v := range m {
+			if x, ok := a.Get(k); !ok || x != v {
+				t.Fatalf("Get(%v) = %v; want %v", k, x, v)
+			}
+		}
+	}
+}
+
+// the same seed and updates must build the same tree
+func TestSeeded(t *testing.T) {
+	shape := func(seed int64) []int {
+		tree := NewSeeded[int, int](seed)
+		for _, k := range rand.New(rand.NewSource(7)).Perm(500) {
+			tree.Put(k, k)
+		}
+		var pre []int
+		var walk func(*Node[int, int])
+		walk = func(x *Node[int, int]) {
+			if x != nil {
+				pre = append(pre, x.key)
+				walk(x.left)
+				walk(x.right)
+			}
+		}
+		walk(tree.root)
+		return pre
+	}
+	if !slices.Equal(shape(42), shape(42)) {
+		t.Errorf("seed 42 built different trees")
+	}
+	if slices.Equal(shape(42), shape(43)) {
+		t.Errorf("seeds 42 and 43 built the same tree")
+	}
+}
@@ -0,0 +1,138 @@
+package ttl
+
+import (
+	"time"
+
+	"golang.org/x/exp/constraints"
+
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// TtlRBT is an ordered map whose entries can be given a time to live.
+// Expired entries are dropped lazily by Get and skipped by iteration.
+// ExpireNow removes them eagerly using a second tree ordered by deadline,
+// so a sweep only visits the entries that are actually due.
+type TtlRBT[K constraints.Ordered, V any] struct {
+	tree      *gm.GeminiRBT[K, entry[V]]
+	deadlines *gm.GeminiRBT[int64, []K] // deadline -> keys that expire then
+	now       func() time.Time
+}
+
+// entry is a value along with its deadline in unix nanoseconds.
+// a zero deadline means the entry never expires
+type entry[V any] struct {
+	val      V
+	deadline int64
+}
+
+// create a new tree that uses the system clock
+func NewRBT[K constraints.Ordered, V any]() *TtlRBT[K, V] {
+	return NewRBTWithClock[K, V](time.Now)
+}
+
+// create a new tree that reads the time from now
+func NewRBTWithClock[K constraints.Ordered, V any](now func() time.Time) *TtlRBT[K, V] {
+	return &TtlRBT[K, V]{
+		tree:      gm.NewRBT[K, entry[V]](),
+		deadlines: gm.NewRBT[int64, []K](),
+		now:       now,
+	}
+}
+
+// insert a key-value pair that never expires
+func (t *TtlRBT[K, V]) Put(key K, val V) {
+	t.tree.Put(key, entry[V]{val: val})
+}
+
+// insert a key-value pair that expires after ttl. a ttl <= 0 never expires
+func (t *TtlRBT[K, V]) PutWithTTL(key K, val V, ttl time.Duration) {
+	if ttl <= 0 {
+		t.Put(key, val)
+		return
+	}
+	deadline := t.now().Add(ttl).UnixNano()
+	t.tree.Put(key, entry[V]{val: val, deadline: deadline})
+
+	// a previous deadline for this key is left in place, ExpireNow
+	// ignores it because it no longer matches the entry
+	keys, _ := t.deadlines.Get(deadline)
+	t.deadlines.Put(deadline, append(keys, key))
+}
+
+// get the value of a key, removing it if it has expired
+func (t *TtlRBT[K, V]) Get(key K) (V, bool) {
+	e, ok := t.tree.Get(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if e.expired(t.now().UnixNano()) {
+		t.tree.Delete(key)
+		var zero V
+		return zero, false
+	}
+	return e.val, true
+}
+
+// remove a key from the tree
+func (t *TtlRBT[K, V]) Delete(key K) {
+	t.tree.Delete(key)
+}
+
+// check if the tree has no unexpired entries
+func (t *TtlRBT[K, V]) IsEmpty() bool {
+	for range t.Iterator() {
+		return false
+	}
+	return true
+}
+
+// remove every entry whose deadline has passed and return how many were removed
+func (t *TtlRBT[K, V]) ExpireNow() int {
+	now := t.now().UnixNano()
+	n := 0
+	for {
+		deadline, ok := t.deadlines.Min()
+		if !ok || deadline > now {
+			break
+		}
+		keys, _ := t.deadlines.Get(deadline)
+		for _, k := range keys {
+			if e, ok := t.tree.Get(k); ok && e.deadline == deadline {
+				t.tree.Delete(k)
+				n++
+			}
+		}
+		t.deadlines.DeleteMin()
+	}
+	return n
+}
+
+func (e entry[V]) expired(now int64) bool {
+	return e.deadline != 0 && e.deadline <= now
+}
+
+// get all unexpired key-value pairs in order
+func (t *TtlRBT[K, V]) GetAll() []rbt.KeyValuePair[K, V] {
+	pairs := make([]rbt.KeyValuePair[K, V], 0)
+	for r := range t.Iterator() {
+		pairs = append(pairs, r)
+	}
+	return pairs
+}
+
+// iterate over the unexpired key-value pairs in order
+func (t *TtlRBT[K, V]) Iterator() func(func(rbt.KeyValuePair[K, V]) bool) {
+	return func(yield func(rbt.KeyValuePair[K, V]) bool) {
+		now := t.now().UnixNano()
+		for r := range t.tree.Iterator() {
+			if r.Val.expired(now) {
+				continue
+			}
+			if !yield(rbt.KeyValuePair[K, V]{Key: r.Key, Val: r.Val.val}) {
+				return
+			}
+		}
+	}
+}
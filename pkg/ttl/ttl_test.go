@@ -0,0 +1,112 @@
+package ttl
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// clock is a manually advanced time source
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestRBT() (*TtlRBT[int, string], *clock) {
+	c := &clock{t: time.Unix(1000, 0)}
+	return NewRBTWithClock[int, string](c.now), c
+}
+
+func keys(t *TtlRBT[int, string]) []int {
+	k := make([]int, 0)
+	for r := range t.Iterator() {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+func TestEmptyRbt(t *testing.T) {
+	rbt, _ := newTestRBT()
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+// test that Get drops an entry once its ttl has passed
+func TestGetExpires(t *testing.T) {
+	rbt, c := newTestRBT()
+	rbt.PutWithTTL(1, "one", time.Second)
+	rbt.Put(2, "two")
+
+	if v, ok := rbt.Get(1); !ok || v != "one" {
+		t.Errorf("Get(1) = %v, %v; want one, true", v, ok)
+	}
+
+	c.advance(time.Second)
+	if v, ok := rbt.Get(1); ok {
+		t.Errorf("Get(1) = %v after expiry; want false", v)
+	}
+	if v, ok := rbt.Get(2); !ok || v != "two" {
+		t.Errorf("Get(2) = %v, %v; want two, true", v, ok)
+	}
+}
+
+// test that iteration skips expired entries
+func TestIteratorSkipsExpired(t *testing.T) {
+	rbt, c := newTestRBT()
+	rbt.PutWithTTL(1, "one", time.Second)
+	rbt.PutWithTTL(2, "two", 3*time.Second)
+	rbt.Put(3, "three")
+	rbt.PutWithTTL(4, "four", 2*time.Second)
+
+	c.advance(2 * time.Second)
+	if got, want := keys(rbt), []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %v; want %v", got, want)
+	}
+	if got := len(rbt.GetAll()); got != 2 {
+		t.Errorf("len(GetAll()) = %v; want 2", got)
+	}
+
+	c.advance(time.Hour)
+	rbt.Delete(3)
+	if !rbt.IsEmpty() {
+		t.Errorf("IsEmpty() == %v; want true", rbt.IsEmpty())
+	}
+}
+
+// test that ExpireNow removes only due entries and ignores replaced deadlines
+func TestExpireNow(t *testing.T) {
+	rbt, c := newTestRBT()
+	for i := 0; i < 10; i++ {
+		rbt.PutWithTTL(i, "", time.Duration(i+1)*time.Second)
+	}
+	// extend 0 and make 1 permanent, their old deadlines become stale
+	rbt.PutWithTTL(0, "", time.Minute)
+	rbt.Put(1, "")
+
+	c.advance(5 * time.Second)
+	if n := rbt.ExpireNow(); n != 3 {
+		t.Errorf("ExpireNow() = %v; want 3", n)
+	}
+	if got, want := keys(rbt), []int{0, 1, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %v; want %v", got, want)
+	}
+
+	c.advance(time.Hour)
+	if n := rbt.ExpireNow(); n != 6 {
+		t.Errorf("ExpireNow() = %v; want 6", n)
+	}
+	if n := rbt.ExpireNow(); n != 0 {
+		t.Errorf("ExpireNow() = %v; want 0", n)
+	}
+	if got, want := keys(rbt), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("Iterator() = %v; want %v", got, want)
+	}
+}
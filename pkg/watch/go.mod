@@ -0,0 +1,5 @@
+module sqirvy.xyz/go-tree-iterator/watch
+
+go 1.22.5
+
+require golang.org/x/exp v0.0.0-20240707233637-46b078467d37
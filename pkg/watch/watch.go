@@ -0,0 +1,239 @@
+package watch
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
+
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+// Policy decides what Publish does when a subscriber is not keeping up
+type Policy int
+
+const (
+	// Block makes Publish, and so the tree mutation, wait until the
+	// subscriber has room
+	Block Policy = iota
+	// Drop discards events that do not fit in the buffer and counts them
+	Drop
+	// Coalesce never waits: undelivered events for the same key are merged
+	// into one event carrying the oldest Old and the newest New value
+	Coalesce
+)
+
+// Hub fans out tree change events to subscribers watching key ranges.
+// Register Publish as the tree's change callback, e.g. tree.OnChange(hub.Publish).
+type Hub[K constraints.Ordered, V any] struct {
+	mu   sync.Mutex
+	subs map[*Subscription[K, V]]struct{}
+}
+
+// create a hub with no subscribers
+func NewHub[K constraints.Ordered, V any]() *Hub[K, V] {
+	return &Hub[K, V]{subs: make(map[*Subscription[K, V]]struct{})}
+}
+
+// Subscription receives the events for keys in [lo..hi] on C until Close
+type Subscription[K constraints.Ordered, V any] struct {
+	C <-chan rbt.Event[K, V]
+
+	hub     *Hub[K, V]
+	lo, hi  K
+	policy  Policy
+	ch      chan rbt.Event[K, V]
+	done    chan struct{}
+	closed  sync.Once
+	dropped atomic.Int64
+
+	// Coalesce only: pending changes by key, in the order keys first changed
+	mu      sync.Mutex
+	pending map[K]change[V]
+	order   []K
+	wake    chan struct{}
+}
+
+// subscribe to changes of keys in [lo..hi]. buffer is the channel capacity
+// for Block and Drop, Coalesce delivers through an unbuffered channel
+func (h *Hub[K, V]) Watch(lo K, hi K, policy Policy, buffer int) *Subscription[K, V] {
+	s := &Subscription[K, V]{
+		hub:    h,
+		lo:     lo,
+		hi:     hi,
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	if policy == Coalesce {
+		s.ch = make(chan rbt.Event[K, V])
+		s.pending = make(map[K]change[V])
+		s.wake = make(chan struct{}, 1)
+		go s.deliver()
+	} else {
+		s.ch = make(chan rbt.Event[K, V], max(buffer, 0))
+	}
+	s.C = s.ch
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// send an event to every subscriber whose range contains its key
+func (h *Hub[K, V]) Publish(ev rbt.Event[K, V]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if ev.Key < s.lo || ev.Key > s.hi {
+			continue
+		}
+		switch s.policy {
+		case Block:
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+			}
+		case Drop:
+			select {
+			case s.ch <- ev:
+			default:
+				s.dropped.Add(1)
+			}
+		case Coalesce:
+			s.coalesce(ev)
+		}
+	}
+}
+
+// get the number of events discarded by a Drop subscription
+func (s *Subscription[K, V]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// unsubscribe. C is closed once no more events can be sent on it;
+// events still buffered in C can be drained before it reports closed
+func (s *Subscription[K, V]) Close() {
+	s.closed.Do(func() {
+		// release a Publish blocked on this subscriber before taking the hub lock
+		close(s.done)
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+		if s.policy != Coalesce {
+			close(s.ch)
+		}
+	})
+}
+
+// ************ coalescing ************
+
+// change is the net effect of the undelivered events for one key: the
+// state the subscriber last saw and the current state
+type change[V any] struct {
+	hadOld bool
+	old    V
+	hasNew bool
+	new    V
+	seq    uint64 // bumped on every merge
+}
+
+// merge ev into the pending change for its key
+func (s *Subscription[K, V]) coalesce(ev rbt.Event[K, V]) {
+	s.mu.Lock()
+	c, ok := s.pending[ev.Key]
+	if !ok {
+		c = change[V]{hadOld: ev.Kind != rbt.EventPut, old: ev.Old}
+		s.order = append(s.order, ev.Key)
+	}
+	c.hasNew = ev.Kind != rbt.EventDelete
+	c.new = ev.New
+	c.seq++
+	s.pending[ev.Key] = c
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// toEvent returns the event that takes a subscriber from the old state of
+// a change to the new one. it reports false when both states are absent
+func toEvent[K constraints.Ordered, V any](key K, c change[V]) (rbt.Event[K, V], bool) {
+	switch {
+	case !c.hadOld && !c.hasNew:
+		return rbt.Event[K, V]{}, false
+	case !c.hadOld:
+		return rbt.Event[K, V]{Kind: rbt.EventPut, Key: key, New: c.new}, true
+	case !c.hasNew:
+		return rbt.Event[K, V]{Kind: rbt.EventDelete, Key: key, Old: c.old}, true
+	}
+	return rbt.Event[K, V]{Kind: rbt.EventUpdate, Key: key, Old: c.old, New: c.new}, true
+}
+
+// deliver pending changes in order until the subscription is closed.
+// the change at the front stays pending while the send waits, so events
+// that arrive meanwhile are merged into it rather than queued behind it
+func (s *Subscription[K, V]) deliver() {
+	defer close(s.ch)
+	for {
+		key, c, ok := s.peek()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		ev, _ := toEvent(key, c)
+
+		// prefer picking up a merge over sending a stale event
+		select {
+		case <-s.wake:
+			continue
+		default:
+		}
+		select {
+		case s.ch <- ev:
+			s.delivered(key, c)
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// get the change at the front of the queue, dropping keys whose events cancelled out
+func (s *Subscription[K, V]) peek() (K, change[V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.order) > 0 {
+		k := s.order[0]
+		c := s.pending[k]
+		if _, ok := toEvent(k, c); ok {
+			return k, c, true
+		}
+		delete(s.pending, k)
+		s.order = s.order[1:]
+	}
+	var zero K
+	return zero, change[V]{}, false
+}
+
+// record that the subscriber has seen sent. if more events were merged in
+// after it was taken from the queue, the rest is kept relative to sent
+func (s *Subscription[K, V]) delivered(key K, sent change[V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.pending[key]
+	if c.seq == sent.seq {
+		delete(s.pending, key)
+		s.order = s.order[1:]
+		return
+	}
+	c.hadOld = sent.hasNew
+	c.old = sent.new
+	s.pending[key] = c
+}
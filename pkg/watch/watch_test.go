@@ -0,0 +1,177 @@
+package watch
+
+import (
+	"testing"
+	"time"
+
+	gm "sqirvy.xyz/go-tree-iterator/gemini"
+	"sqirvy.xyz/go-tree-iterator/rbt"
+)
+
+func newWatchedRBT() (*gm.GeminiRBT[int, string], *Hub[int, string]) {
+	tree := gm.NewRBT[int, string]()
+	hub := NewHub[int, string]()
+	tree.OnChange(hub.Publish)
+	return tree, hub
+}
+
+func drain(c <-chan rbt.Event[int, string]) []rbt.Event[int, string] {
+	evs := make([]rbt.Event[int, string], 0)
+	for ev := range c {
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// test that each mutation path emits the right event for keys in range
+func TestEvents(t *testing.T) {
+	tree, hub := newWatchedRBT()
+	s := hub.Watch(10, 20, Block, 16)
+
+	tree.Put(5, "out")
+	tree.Put(10, "a")
+	tree.Put(10, "b")
+	tree.Put(15, "c")
+	tree.Delete(10)
+	tree.Delete(11)
+	tree.Put(20, "d")
+	tree.DeleteMax()
+	tree.DeleteMin()
+	s.Close()
+
+	want := []rbt.Event[int, string]{
+		{Kind: rbt.EventPut, Key: 10, New: "a"},
+		{Kind: rbt.EventUpdate, Key: 10, Old: "a", New: "b"},
+		{Kind: rbt.EventPut, Key: 15, New: "c"},
+		{Kind: rbt.EventDelete, Key: 10, Old: "b"},
+		{Kind: rbt.EventPut, Key: 20, New: "d"},
+		{Kind: rbt.EventDelete, Key: 20, Old: "d"},
+	}
+	got := drain(s.C)
+	if len(got) != len(want) {
+		t.Fatalf("got %v events; want %v: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %v = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+
+	// the subscription is gone, further writes do not block
+	tree.Put(12, "x")
+}
+
+// test that Drop discards what does not fit and never blocks the writer
+func TestDrop(t *testing.T) {
+	tree, hub := newWatchedRBT()
+	s := hub.Watch(0, 100, Drop, 3)
+	for i := 0; i < 10; i++ {
+		tree.Put(i, "v")
+	}
+	s.Close()
+
+	if got := drain(s.C); len(got) != 3 {
+		t.Errorf("got %v events; want 3", len(got))
+	}
+	if s.Dropped() != 7 {
+		t.Errorf("Dropped() = %v; want 7", s.Dropped())
+	}
+}
+
+// test that Close releases a writer blocked on a full subscriber
+func TestBlockClose(t *testing.T) {
+	tree, hub := newWatchedRBT()
+	s := hub.Watch(0, 100, Block, 0)
+
+	done := make(chan struct{})
+	go func() {
+		tree.Put(1, "one")
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("Put did not block on a full subscriber")
+	default:
+	}
+	s.Close()
+	<-done
+}
+
+// test that Coalesce merges the events of a slow subscriber by key into
+// a stream that replays to the same final state
+func TestCoalesce(t *testing.T) {
+	tree, hub := newWatchedRBT()
+	tree.Put(3, "old")
+	s := hub.Watch(0, 100, Coalesce, 0)
+
+	// nothing reads until all writes are done
+	tree.Put(1, "a")
+	tree.Put(1, "b")
+	tree.Put(2, "x")
+	tree.Delete(2)
+	tree.Put(3, "mid")
+	tree.Put(3, "new")
+	tree.Delete(1)
+	tree.Put(1, "c")
+
+	// replay the events on a copy of the tree as the subscriber saw it
+	mirror := map[int]string{3: "old"}
+	n := 0
+	timeout := time.After(time.Second)
+	for len(mirror) != 2 || mirror[1] != "c" || mirror[3] != "new" {
+		select {
+		case ev := <-s.C:
+			n++
+			old, had := mirror[ev.Key]
+			switch ev.Kind {
+			case rbt.EventPut:
+				if had {
+					t.Errorf("put of existing key %v", ev.Key)
+				}
+				mirror[ev.Key] = ev.New
+			case rbt.EventUpdate:
+				if !had || old != ev.Old {
+					t.Errorf("update %+v; subscriber has %v, %v", ev, old, had)
+				}
+				mirror[ev.Key] = ev.New
+			case rbt.EventDelete:
+				if !had || old != ev.Old {
+					t.Errorf("delete %+v; subscriber has %v, %v", ev, old, had)
+				}
+				delete(mirror, ev.Key)
+			}
+		case <-timeout:
+			t.Fatalf("timed out with subscriber state %v", mirror)
+		}
+	}
+	s.Close()
+	if got := drain(s.C); len(got) != 0 {
+		t.Errorf("extra events %v", got)
+	}
+	// 8 writes, the x put and delete cancel out
+	if n > 4 {
+		t.Errorf("got %v events; want them coalesced", n)
+	}
+}
+
+// test the net effect of merged changes
+func TestToEvent(t *testing.T) {
+	tests := []struct {
+		c    change[string]
+		want rbt.Event[int, string]
+		ok   bool
+	}{
+		{change[string]{hasNew: true, new: "b"}, rbt.Event[int, string]{Kind: rbt.EventPut, Key: 1, New: "b"}, true},
+		{change[string]{hadOld: true, old: "a", hasNew: true, new: "b"}, rbt.Event[int, string]{Kind: rbt.EventUpdate, Key: 1, Old: "a", New: "b"}, true},
+		{change[string]{hadOld: true, old: "a", new: "b"}, rbt.Event[int, string]{Kind: rbt.EventDelete, Key: 1, Old: "a"}, true},
+		{change[string]{new: "b"}, rbt.Event[int, string]{}, false},
+	}
+	for _, tc := range tests {
+		ev, ok := toEvent(1, tc.c)
+		if ev != tc.want || ok != tc.ok {
+			t.Errorf("toEvent(%+v) = %+v, %v; want %+v, %v", tc.c, ev, ok, tc.want, tc.ok)
+		}
+	}
+}